@@ -6,20 +6,37 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"os/user"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/network"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/jm"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/jobstore"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
 )
 
 func main() {
 	statusFlag := flag.String("job-status", "", "Display the status of various jobs; comma-separated list of job IDs")
 	runningJobsFlag := flag.String("running-jobs", "", "Display how many jobs are already running on the target (e.g., a Slurm partition)")
+	accountingFlag := flag.String("accounting", "", "Display the accounting data of various completed jobs; comma-separated list of job IDs")
+	netDevicesFlag := flag.Bool("net-devices", false, "Display the network devices that are available on the local node")
+	sysfsFlag := flag.String("sysfs", network.DefaultSysfsRoot, "Mount point of sysfs, used to discover the network devices")
+	batchFlag := flag.String("batch", "", "Submit the batch scripts listed in a file, one per line, through a submission queue")
+	maxJobsFlag := flag.Int("max-jobs", 10, "Maximum number of jobs pending or running on the target when submitting in batch mode")
+	partitionFlag := flag.String("partition", "", "Target of the jobs submitted in batch mode (e.g., a Slurm partition)")
+	pollFlag := flag.Duration("poll-interval", 30*time.Second, "How often the job manager is queried while the maximum number of jobs is reached in batch mode")
+	storeDirFlag := flag.String("store-dir", jobstore.DefaultDir(), "Directory of the job store, where submitted jobs are recorded")
 	help := flag.Bool("h", false, "Help message")
 
 	flag.Parse()
@@ -28,10 +45,32 @@ func main() {
 	if *help {
 		fmt.Printf("%s is a command line tool to query any supported job manager", cmdName)
 		fmt.Println("\nUsage:")
+		fmt.Printf("  %s [options] [list | show <job key or ID> | logs [-f] <job key or ID> | compact]\n", cmdName)
 		flag.PrintDefaults()
 		os.Exit(0)
 	}
 
+	if flag.NArg() > 0 {
+		err := runCommand(*storeDirFlag, flag.Args())
+		if err != nil {
+			fmt.Printf("ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *netDevicesFlag {
+		devices, err := network.Discover(*sysfsFlag)
+		if err != nil {
+			fmt.Printf("ERROR: unable to discover the network devices: %s\n", err)
+			os.Exit(1)
+		}
+		for _, d := range devices {
+			fmt.Printf("%s\t%s\t%s\t%s\n", d.ID(), d.Type, d.State, d.Rate)
+		}
+		return
+	}
+
 	jobmgr := jm.Detect()
 	if *statusFlag != "" {
 		jobIDsStr := strings.Split(*statusFlag, ",")
@@ -59,6 +98,24 @@ func main() {
 		}
 	}
 
+	if *accountingFlag != "" {
+		for _, w := range strings.Split(*accountingFlag, ",") {
+			jobID, err := strconv.Atoi(w)
+			if err != nil {
+				fmt.Printf("ERROR: invalid job ID: %s\n", w)
+				os.Exit(1)
+			}
+			a, err := jobmgr.Accounting(&job.Job{ID: jobID})
+			if err != nil {
+				fmt.Printf("ERROR: unable to retrieve the accounting data of job %d: %s\n", jobID, err)
+				os.Exit(1)
+			}
+			fmt.Printf("%d: state=%s exit_code=%d signal=%d start=%s end=%s elapsed=%s nodes=%s max_rss=%d cpu_time=%s\n",
+				a.JobID, a.State, a.ExitCode, a.Signal, a.Start.Format(time.RFC3339), a.End.Format(time.RFC3339),
+				a.Elapsed, strings.Join(a.Nodes, ","), a.MaxRSS, a.CPUTime)
+		}
+	}
+
 	if *runningJobsFlag != "" {
 		u, err := user.Current()
 		if err != nil {
@@ -72,4 +129,139 @@ func main() {
 		}
 		fmt.Printf("Number of running jobs: %d\n", num)
 	}
+
+	if *batchFlag != "" {
+		err := runBatch(&jobmgr, *batchFlag, *storeDirFlag, *partitionFlag, *maxJobsFlag, *pollFlag)
+		if err != nil {
+			fmt.Printf("ERROR: %s\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// runBatch submits the batch scripts listed in a file through a submission queue and displays the progress
+func runBatch(jobmgr *jm.JM, listFile string, storeDir string, partition string, maxJobs int, pollInterval time.Duration) error {
+	if jobmgr.ID != jm.SlurmID && jobmgr.ID != jm.IntelSlurmID {
+		return fmt.Errorf("batch mode is not supported with the %s job manager", jobmgr.ID)
+	}
+	content, err := os.ReadFile(listFile)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", listFile, err)
+	}
+
+	var sysCfg sys.Config
+	sysCfg.CurPath, err = os.Getwd()
+	if err != nil {
+		return fmt.Errorf("unable to get the current directory: %w", err)
+	}
+	sysCfg.ScratchDir, err = os.MkdirTemp("", "jobmgr-")
+	if err != nil {
+		return fmt.Errorf("unable to create a scratch directory: %w", err)
+	}
+	defer os.RemoveAll(sysCfg.ScratchDir)
+
+	sysCfg.JobStoreDir = storeDir
+	q := jm.Queue{
+		MaxJobs:      maxJobs,
+		PollInterval: pollInterval,
+		OnProgress: func(p *jm.Progress) {
+			fmt.Printf("[%d/%d completed, %d running, %d pending] %s: %s\n", p.Completed, p.Total, p.Running, p.Pending, p.Job.BatchScript, p.Event)
+		},
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		q.Add(&job.Job{Name: filepath.Base(line), BatchScript: line, Partition: partition, RunDir: filepath.Dir(line)})
+	}
+
+	err = q.Run(jobmgr, &sysCfg)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, e := range q.Entries() {
+		if !e.Result.Success() {
+			failed++
+			fmt.Printf("%s (job %d): %s\n", e.Job.BatchScript, e.Job.ID, e.Result)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d job(s) out of %d failed", failed, len(q.Entries()))
+	}
+	return nil
+}
+
+// runCommand runs a command querying the job store
+func runCommand(storeDir string, args []string) error {
+	s, err := jobstore.Open(storeDir)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		records, err := s.List(&jobstore.Query{})
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tNAME\tBACKEND\tJOBID\tSTATE\tSUBMITTED")
+		for _, r := range records {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", r.Key, r.Name, r.Backend, r.JobID, r.State, r.Submitted().Format(time.RFC3339))
+		}
+		return w.Flush()
+	case "show":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: show <job key or ID>")
+		}
+		r, err := s.Find(args[1])
+		if err != nil {
+			return err
+		}
+		out, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to encode job %s: %w", args[1], err)
+		}
+		fmt.Println(string(out))
+		return nil
+	case "logs":
+		return showLogs(s, args[1:])
+	case "compact":
+		return s.Compact()
+	}
+	return fmt.Errorf("unknown command: %s", args[0])
+}
+
+// showLogs displays the output of a job recorded in the job store or, with -f, follows it until the job completes
+func showLogs(s *jobstore.Store, args []string) error {
+	flags := flag.NewFlagSet("logs", flag.ContinueOnError)
+	follow := flags.Bool("f", false, "Follow the output of the job until it completes")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: logs [-f] <job key or ID>")
+	}
+	r, err := s.Find(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	jobmgr := jm.Detect()
+	sysCfg := sys.Config{JobStoreDir: s.Dir}
+	j, err := jobmgr.Reattach(r.JobID, &sysCfg)
+	if err != nil {
+		return fmt.Errorf("unable to reattach to job %d: %w", r.JobID, err)
+	}
+	if !*follow {
+		fmt.Print(j.GetOutput(&sysCfg))
+		fmt.Fprint(os.Stderr, j.GetError(&sysCfg))
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return jobmgr.Follow(ctx, j, &sysCfg, os.Stdout, os.Stderr, 0)
 }
@@ -1,4 +1,4 @@
-// Copyright (c) 2022, NVIDIA CORPORATION. All rights reserved.
+// Copyright (c) 2022-2025, NVIDIA CORPORATION. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -10,12 +10,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/gvallee/go_hpc_jobmgr/pkg/mpi"
 )
 
 func main() {
 	dirFlag := flag.String("dir", "", "Path to the install directory where the MPI is installed")
+	scanFlag := flag.Bool("scan", false, "Scan the system (PATH, modules, Spack) for all the MPI installations")
+	rootsFlag := flag.String("roots", "", "Comma-separated list of directories to scan for MPI installations (with -scan)")
 	help := flag.Bool("h", false, "Help message")
 
 	flag.Parse()
@@ -28,6 +31,22 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *scanFlag {
+		cfg := mpi.DefaultScanConfig()
+		if *rootsFlag != "" {
+			cfg.Roots = strings.Split(*rootsFlag, ",")
+		}
+		mpis, err := mpi.Scan(cfg)
+		if err != nil {
+			fmt.Printf("unable to scan the system for MPI installations: %s\n", err)
+			os.Exit(1)
+		}
+		for _, i := range mpis {
+			fmt.Printf("%s\t%s\t%s\t%s (%s)\n", i.ID, i.Version, i.InstallDir, i.Source, i.SourceRef)
+		}
+		return
+	}
+
 	i, err := mpi.DetectFromDir(*dirFlag)
 	if err != nil {
 		fmt.Printf("unable to detect the MPI implementation installed in %s: %s\n", *dirFlag, err)
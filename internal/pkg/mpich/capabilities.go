@@ -0,0 +1,159 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package mpich
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MPICHVersionBin is the name of the binary giving the details of the MPICH build
+	MPICHVersionBin = "mpichversion"
+
+	// NetmodUCX is the name of the UCX network module
+	NetmodUCX = "ucx"
+
+	// NetmodOFI is the name of the libfabric network module
+	NetmodOFI = "ofi"
+)
+
+var configureOptionRegexp = regexp.MustCompile(`'([^']*)'`)
+
+var netmodInlineRegexp = regexp.MustCompile(`-DNETMOD_INLINE=__netmod_inline_([a-z]+)__`)
+
+// Capabilities describes what a specific build of MPICH supports
+type Capabilities struct {
+	// Version is the version of MPICH
+	Version string
+
+	// ReleaseDate is the release date of MPICH
+	ReleaseDate string
+
+	// CC is the C compiler used to build MPICH
+	CC string
+
+	// ConfigureOptions is the list of options that were used to configure MPICH
+	ConfigureOptions []string
+
+	// ProcessManager is the process manager interface (e.g., pmi)
+	ProcessManager string
+
+	// Launchers is the list of launchers supported by Hydra (e.g., ssh, slurm)
+	Launchers []string
+
+	// TopologyLibraries is the list of topology libraries supported by Hydra (e.g., hwloc)
+	TopologyLibraries []string
+
+	// RMKs is the list of resource management kernels supported by Hydra (e.g., slurm, pbs)
+	RMKs []string
+
+	// DemuxEngines is the list of demux engines supported by Hydra
+	DemuxEngines []string
+
+	// Device is the MPICH device (e.g., ch3, ch4)
+	Device string
+
+	// Netmod is the network module used by the device (e.g., ucx, ofi)
+	Netmod string
+}
+
+func contains(list []string, elt string) bool {
+	for _, e := range list {
+		if e == elt {
+			return true
+		}
+	}
+	return false
+}
+
+// HasLauncher checks whether Hydra supports a given launcher
+func (c *Capabilities) HasLauncher(launcher string) bool {
+	return contains(c.Launchers, launcher)
+}
+
+// HasRMK checks whether Hydra supports a given resource management kernel
+func (c *Capabilities) HasRMK(rmk string) bool {
+	return contains(c.RMKs, rmk)
+}
+
+func (c *Capabilities) setDevice(device string) {
+	tokens := strings.SplitN(device, ":", 2)
+	c.Device = tokens[0]
+	if len(tokens) == 2 {
+		c.Netmod = tokens[1]
+	}
+}
+
+// ParseCapabilities parses the HYDRA build details from the output of 'mpirun --version'
+func ParseCapabilities(output string) (*Capabilities, error) {
+	if !strings.Contains(output, "HYDRA build details:") {
+		return nil, fmt.Errorf("invalid output format")
+	}
+
+	c := new(Capabilities)
+	for _, line := range strings.Split(output, "\n") {
+		tokens := strings.SplitN(line, ":", 2)
+		if len(tokens) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(tokens[0])
+		value := strings.TrimSpace(tokens[1])
+		switch key {
+		case "Version":
+			c.Version = value
+		case "Release Date":
+			c.ReleaseDate = value
+		case "CC":
+			c.CC = value
+		case "Configure options":
+			for _, m := range configureOptionRegexp.FindAllStringSubmatch(value, -1) {
+				c.ConfigureOptions = append(c.ConfigureOptions, m[1])
+			}
+		case "Process Manager":
+			c.ProcessManager = value
+		case "Launchers available":
+			c.Launchers = strings.Fields(value)
+		case "Topology libraries available":
+			c.TopologyLibraries = strings.Fields(value)
+		case "Resource management kernels available":
+			c.RMKs = strings.Fields(value)
+		case "Demux engines available":
+			c.DemuxEngines = strings.Fields(value)
+		}
+	}
+
+	for _, opt := range c.ConfigureOptions {
+		if strings.HasPrefix(opt, "--with-device=") {
+			c.setDevice(strings.TrimPrefix(opt, "--with-device="))
+		}
+	}
+	if c.Netmod == "" {
+		// MPICH 3.4 and later inline the netmod, which shows up in the CPPFLAGS
+		for _, opt := range c.ConfigureOptions {
+			m := netmodInlineRegexp.FindStringSubmatch(opt)
+			if m != nil {
+				c.Device = "ch4"
+				c.Netmod = m[1]
+				break
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// ParseMPICHVersionOutput completes the capabilities with the output of 'mpichversion',
+// which gives the device used by MPICH (e.g., "MPICH Device: ch4:ofi")
+func (c *Capabilities) ParseMPICHVersionOutput(output string) {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "MPICH Device:") {
+			c.setDevice(strings.TrimSpace(strings.TrimPrefix(line, "MPICH Device:")))
+		}
+	}
+}
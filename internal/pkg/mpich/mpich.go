@@ -7,11 +7,14 @@ package mpich
 
 import (
 	"fmt"
-	"path/filepath"
+	"strconv"
 	"strings"
 
-	"github.com/gvallee/go_exec/pkg/advexec"
-	"github.com/gvallee/go_util/pkg/util"
+	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/network"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mapping"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/nodelist"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/params"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
 )
 
 const (
@@ -23,12 +26,198 @@ const (
 	TarballTag = "MPICHTARBALL"
 	// ID is the internal ID for MPICH
 	ID = "mpich"
+	// VersionBin is the name of the binary used to detect and query MPICH
+	VersionBin = "mpirun"
+	// VersionArg is the argument to pass to VersionBin to get the version of MPICH
+	VersionArg = "--version"
 )
 
-// GetExtraMpirunArgs returns the extra mpirun arguments required by MPICH for a specific configuration
-func GetExtraMpirunArgs() []string {
-	var extraArgs []string
-	return extraArgs
+// GetParams returns the environment variables required by MPICH for a specific configuration
+func GetParams(sys *sys.Config, netCfg *network.Config, caps *Capabilities) *params.Set {
+	set := params.New()
+	if netCfg == nil {
+		return set
+	}
+	if netCfg.Device != "" && !netCfg.UsesOFI() && (caps == nil || caps.Netmod != NetmodOFI) {
+		_ = set.SetRequired(params.KindEnv, "UCX_NET_DEVICES", netCfg.UCXNetDevices())
+	}
+	if netCfg.UsesUCX() {
+		_ = set.SetRequired(params.KindEnv, "UCX_TLS", strings.Join(netCfg.UCXTLS, ","))
+	}
+	if netCfg.UsesOFI() {
+		_ = set.SetRequired(params.KindEnv, "FI_PROVIDER", netCfg.OFIProvider)
+	}
+	return set
+}
+
+// ValidateParams checks that a set of parameters can be used with MPICH
+func ValidateParams(set *params.Set) error {
+	for _, p := range set.Params() {
+		if p.Kind != params.KindEnv {
+			return fmt.Errorf("MPICH does not support %s parameters (%s)", p.Kind, p.Name)
+		}
+	}
+	return nil
+}
+
+// GetParamArgs translates a set of parameters into Hydra arguments
+func GetParamArgs(set *params.Set) []string {
+	var args []string
+	for _, p := range set.Params() {
+		if p.Kind == params.KindEnv {
+			args = append(args, "-genv", p.Name+"="+p.Value)
+		}
+	}
+	return args
+}
+
+// ParseParamArgs extracts the environment variables (-genv NAME VALUE or -genv NAME=VALUE) from a list
+// of Hydra arguments. The other arguments are returned as they are.
+func ParseParamArgs(args []string) (*params.Set, []string, error) {
+	set := params.New()
+	var others []string
+	for idx := 0; idx < len(args); idx++ {
+		if args[idx] != "-genv" {
+			others = append(others, args[idx])
+			continue
+		}
+		if idx+1 >= len(args) {
+			return nil, nil, fmt.Errorf("missing name for -genv")
+		}
+		var p params.Param
+		var err error
+		if strings.Contains(args[idx+1], "=") {
+			p, err = params.Parse(params.KindEnv, args[idx+1])
+			idx++
+		} else {
+			if idx+2 >= len(args) {
+				return nil, nil, fmt.Errorf("missing value for -genv %s", args[idx+1])
+			}
+			p = params.Param{Kind: params.KindEnv, Name: args[idx+1], Value: args[idx+2], Priority: params.PriorityUser}
+			idx += 2
+		}
+		if err == nil {
+			err = set.Add(p)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return set, others, nil
+}
+
+// GetInterfaceArgs returns the Hydra arguments selecting the network interface to use
+func GetInterfaceArgs(netCfg *network.Config) []string {
+	var args []string
+	if netCfg != nil && len(netCfg.TCPInclude) > 0 {
+		args = append(args, "-iface", netCfg.TCPInclude[0])
+	}
+	return args
+}
+
+// GetExtraMpirunArgs returns the extra mpirun arguments required by MPICH for a specific configuration,
+// appended to extraArgs
+func GetExtraMpirunArgs(sys *sys.Config, netCfg *network.Config, caps *Capabilities, extraArgs []string) []string {
+	extraArgs = append(extraArgs, GetParamArgs(GetParams(sys, netCfg, caps))...)
+	return append(extraArgs, GetInterfaceArgs(netCfg)...)
+}
+
+// ValidateNetwork checks that a network configuration can be used with a build of MPICH.
+// caps is optional; when unknown, only the consistency of the configuration is checked.
+func ValidateNetwork(netCfg *network.Config, caps *Capabilities) error {
+	err := netCfg.Validate()
+	if err != nil {
+		return err
+	}
+	if netCfg == nil {
+		return nil
+	}
+	if len(netCfg.TCPInclude) > 1 {
+		return fmt.Errorf("MPICH only supports a single TCP interface")
+	}
+	if len(netCfg.TCPExclude) > 0 {
+		return fmt.Errorf("MPICH does not support excluding TCP interfaces")
+	}
+	if netCfg.UsesUCX() && netCfg.UsesOFI() {
+		return fmt.Errorf("UCX transports and a libfabric provider cannot be used at the same time")
+	}
+	if caps == nil || caps.Netmod == "" {
+		return nil
+	}
+	if netCfg.UsesUCX() && caps.Netmod != NetmodUCX {
+		return fmt.Errorf("MPICH was built with the %s netmod, UCX transports cannot be used", caps.Netmod)
+	}
+	if netCfg.UsesOFI() && caps.Netmod != NetmodOFI {
+		return fmt.Errorf("MPICH was built with the %s netmod, a libfabric provider cannot be used", caps.Netmod)
+	}
+	return nil
+}
+
+// GetLaunchArgs returns the Hydra arguments to use to launch a job within a given resource manager
+// (e.g., "slurm"), based on the launchers and resource management kernels MPICH was built with
+func GetLaunchArgs(caps *Capabilities, resourceManager string) []string {
+	var args []string
+	if caps == nil || resourceManager == "" {
+		return args
+	}
+	if caps.HasLauncher(resourceManager) {
+		args = append(args, "-launcher")
+		args = append(args, resourceManager)
+	}
+	if caps.HasRMK(resourceManager) {
+		args = append(args, "-rmk")
+		args = append(args, resourceManager)
+	}
+	return args
+}
+
+// GetMappingArgs translates a mapping/binding spec into Hydra arguments
+func GetMappingArgs(spec *mapping.Spec) ([]string, error) {
+	var args []string
+	if spec == nil {
+		return args, nil
+	}
+	err := spec.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.RanksPerSocket > 0 || spec.RanksPerNUMA > 0 {
+		return nil, fmt.Errorf("MPICH only supports a number of ranks per node")
+	}
+	if spec.RanksPerNode > 0 {
+		args = append(args, "-ppn", strconv.Itoa(spec.RanksPerNode))
+	}
+	switch spec.RankBy {
+	case "", mapping.RankSlot:
+		// Default behavior of Hydra
+	case mapping.RankCore, mapping.RankSocket, mapping.RankNUMA:
+		args = append(args, "-map-by", spec.RankBy)
+	default:
+		return nil, fmt.Errorf("MPICH does not support ranking by %s", spec.RankBy)
+	}
+	if spec.BindTo != "" {
+		args = append(args, "-bind-to", spec.BindTo)
+	}
+	return args, nil
+}
+
+// GetHostfileContent returns the content of a Hydra machinefile for a list of nodes
+func GetHostfileContent(nodes []nodelist.Node) string {
+	content := ""
+	for _, n := range nodes {
+		if n.Slots > 0 {
+			content += fmt.Sprintf("%s:%d\n", n.Name, n.Slots)
+		} else {
+			content += n.Name + "\n"
+		}
+	}
+	return content
+}
+
+// GetHostfileArgs returns the mpirun arguments to use a given machinefile
+func GetHostfileArgs(path string) []string {
+	return []string{"-f", path}
 }
 
 // GetConfigureExtraArgs returns the extra arguments required to configure MPICH
@@ -37,7 +226,8 @@ func GetConfigureExtraArgs() []string {
 	return extraArgs
 }
 
-func parseMPICHInfoOutputForVersion(output string) (string, error) {
+// ParseVersion extracts the version of MPICH from the output of 'mpirun --version'
+func ParseVersion(output string) (string, error) {
 	targetLineIdx := 1
 	lines := strings.Split(output, "\n")
 	if !strings.Contains(lines[targetLineIdx], "Version:") {
@@ -53,33 +243,3 @@ func parseMPICHInfoOutputForVersion(output string) (string, error) {
 	version = strings.TrimRight(version, "\n")
 	return version, nil
 }
-
-// DetectFromDir tries to figure out which version of MPICH is installed in a given directory
-func DetectFromDir(dir string, env []string) (string, string, error) {
-	targetBin := filepath.Join(dir, "bin", "mpirun")
-	if !util.FileExists(targetBin) {
-		return "", "", fmt.Errorf("%s does not exist, not an MPICH implementation", targetBin)
-	}
-
-	var versionCmd advexec.Advcmd
-	versionCmd.BinPath = targetBin
-	versionCmd.CmdArgs = append(versionCmd.CmdArgs, "--version")
-	versionCmd.ExecDir = filepath.Join(dir, "bin")
-	versionCmd.Env = env
-	if env == nil {
-		newLDPath := filepath.Join(dir, "lib") + ":$LD_LIBRARY_PATH"
-		newPath := filepath.Join(dir, "bin") + ":$PATH"
-		versionCmd.Env = append(versionCmd.Env, "LD_LIBRARY_PATH="+newLDPath)
-		versionCmd.Env = append(versionCmd.Env, "PATH="+newPath)
-	}
-	res := versionCmd.Run()
-	if res.Err != nil {
-		return "", "", fmt.Errorf("unable to execute %s --version: %w", targetBin, res.Err)
-	}
-	version, err := parseMPICHInfoOutputForVersion(res.Stdout)
-	if err != nil {
-		return "", "", fmt.Errorf("parseOmpiInfoOutputForVersion() failed - %w", err)
-	}
-
-	return ID, version, nil
-}
@@ -5,7 +5,13 @@
 
 package mpich
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/network"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mapping"
+)
 
 func TestParseMPICHInfoOutputForVersion(t *testing.T) {
 	tests := []struct {
@@ -44,12 +50,96 @@ func TestParseMPICHInfoOutputForVersion(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		version, err := parseMPICHInfoOutputForVersion(tt.input)
+		version, err := ParseVersion(tt.input)
 		if err != nil {
-			t.Fatalf("parseOmpiInfoOutputForVersion() failed: %s", err)
+			t.Fatalf("ParseVersion() failed: %s", err)
 		}
 		if version != tt.expectedOutput {
-			t.Fatalf("parseOmpiInfoOutputForVersion() returned %s instead of %s", version, tt.expectedOutput)
+			t.Fatalf("ParseVersion() returned %s instead of %s", version, tt.expectedOutput)
 		}
 	}
 }
+
+func TestParseCapabilities(t *testing.T) {
+	output := `HYDRA build details:
+    Version:                                 4.0b1
+    Release Date:                            Mon Nov 15 10:22:52 CST 2021
+    CC:                              gcc      
+    Configure options:                       '--disable-option-checking' '--prefix=/home/gvallee/install/mpich-4.0b1' '--cache-file=/dev/null' '--srcdir=.' 'CC=gcc' 'CFLAGS= -O2' 'CPPFLAGS=-DNETMOD_INLINE=__netmod_inline_ofi__ -D__HIP_PLATFORM_AMD__ -I/home/gvallee/src/mpich-4.0b1/src/mpl/include'
+    Process Manager:                         pmi
+    Launchers available:                     ssh rsh fork slurm ll lsf sge manual persist
+    Topology libraries available:            hwloc
+    Resource management kernels available:   user slurm ll lsf sge pbs cobalt
+    Demux engines available:                 poll select`
+
+	caps, err := ParseCapabilities(output)
+	if err != nil {
+		t.Fatalf("ParseCapabilities() failed: %s", err)
+	}
+	if caps.Version != "4.0b1" || caps.CC != "gcc" || caps.ProcessManager != "pmi" {
+		t.Fatalf("invalid capabilities: %+v", caps)
+	}
+	if len(caps.ConfigureOptions) != 7 || caps.ConfigureOptions[1] != "--prefix=/home/gvallee/install/mpich-4.0b1" {
+		t.Fatalf("invalid configure options: %v", caps.ConfigureOptions)
+	}
+	if caps.Device != "ch4" || caps.Netmod != NetmodOFI {
+		t.Fatalf("invalid device: %s:%s", caps.Device, caps.Netmod)
+	}
+	if !caps.HasLauncher("slurm") || !caps.HasRMK("pbs") || caps.HasLauncher("pbs") {
+		t.Fatalf("invalid launchers or RMKs: %v %v", caps.Launchers, caps.RMKs)
+	}
+
+	caps.ParseMPICHVersionOutput("MPICH Version:      4.0b1\nMPICH Device:       ch4:ucx\n")
+	if caps.Device != "ch4" || caps.Netmod != NetmodUCX {
+		t.Fatalf("invalid device: %s:%s", caps.Device, caps.Netmod)
+	}
+
+	args := strings.Join(GetLaunchArgs(caps, "slurm"), " ")
+	if args != "-launcher slurm -rmk slurm" {
+		t.Fatalf("GetLaunchArgs() returned %q", args)
+	}
+	args = strings.Join(GetLaunchArgs(caps, "pbs"), " ")
+	if args != "-rmk pbs" {
+		t.Fatalf("GetLaunchArgs() returned %q", args)
+	}
+
+	_, err = ParseCapabilities("MPICH Version: 4.0b1")
+	if err == nil {
+		t.Fatalf("ParseCapabilities() succeeded with an invalid output")
+	}
+}
+
+func TestGetMappingArgs(t *testing.T) {
+	spec := &mapping.Spec{RanksPerNode: 4, BindTo: mapping.BindCore, RankBy: mapping.RankSocket}
+	args, err := GetMappingArgs(spec)
+	if err != nil {
+		t.Fatalf("GetMappingArgs() failed: %s", err)
+	}
+	expected := "-ppn 4 -map-by socket -bind-to core"
+	if strings.Join(args, " ") != expected {
+		t.Fatalf("GetMappingArgs() returned %q instead of %q", strings.Join(args, " "), expected)
+	}
+
+	_, err = GetMappingArgs(&mapping.Spec{RanksPerNUMA: 2})
+	if err == nil {
+		t.Fatalf("GetMappingArgs() succeeded with an unsupported number of ranks per NUMA domain")
+	}
+}
+
+func TestValidateNetwork(t *testing.T) {
+	caps := &Capabilities{Device: "ch4", Netmod: NetmodOFI}
+	netCfg := &network.Config{OFIProvider: "cxi", TCPInclude: []string{"hsn0"}}
+	err := ValidateNetwork(netCfg, caps)
+	if err != nil {
+		t.Fatalf("ValidateNetwork() failed: %s", err)
+	}
+	args := strings.Join(GetExtraMpirunArgs(nil, netCfg, caps, nil), " ")
+	if args != "-genv FI_PROVIDER=cxi -iface hsn0" {
+		t.Fatalf("GetExtraMpirunArgs() returned %q", args)
+	}
+
+	err = ValidateNetwork(&network.Config{UCXTLS: []string{"rc"}}, caps)
+	if err == nil {
+		t.Fatalf("ValidateNetwork() succeeded with UCX transports and the ofi netmod")
+	}
+}
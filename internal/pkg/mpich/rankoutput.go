@@ -0,0 +1,68 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package mpich
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// rankOutputPrefix is the prefix of the names of the files where each rank writes its output
+	rankOutputPrefix = "rank."
+
+	// rankOutputSuffix is the suffix of the names of the files where each rank writes stdout
+	rankOutputSuffix = ".out"
+
+	// rankErrorSuffix is the suffix of the names of the files where each rank writes stderr
+	rankErrorSuffix = ".err"
+)
+
+// TaggedOutputRegexp matches a line of output tagged with -prepend-rank, e.g., "[0] hello"
+var TaggedOutputRegexp = regexp.MustCompile(`^\[(\d+)\] ?(.*)$`)
+
+// GetRankOutputArgs returns the Hydra arguments to keep the output of each rank separate: each rank writes
+// to its own files in dir with -outfile-pattern and -errfile-pattern or, if dir is empty, the output is
+// prefixed with the rank (-prepend-rank)
+func GetRankOutputArgs(dir string) []string {
+	if dir == "" {
+		return []string{"-prepend-rank"}
+	}
+	return []string{
+		"-outfile-pattern", filepath.Join(dir, rankOutputPrefix+"%r"+rankOutputSuffix),
+		"-errfile-pattern", filepath.Join(dir, rankOutputPrefix+"%r"+rankErrorSuffix),
+	}
+}
+
+// GetRankOutputFiles returns the paths to the files with stdout and stderr of each rank, written by Hydra
+// in dir when using the arguments returned by GetRankOutputArgs
+func GetRankOutputFiles(dir string) (map[int]string, map[int]string, error) {
+	return FindRankOutputFiles(dir, rankOutputPrefix, rankOutputSuffix, rankErrorSuffix)
+}
+
+// FindRankOutputFiles returns the paths to the files with stdout and stderr of each rank, keyed by rank, when
+// each rank writes to its own files in dir named after its rank, e.g., rank.0.out and rank.0.err
+func FindRankOutputFiles(dir string, prefix string, outSuffix string, errSuffix string) (map[int]string, map[int]string, error) {
+	stdoutFiles := make(map[int]string)
+	stderrFiles := make(map[int]string)
+	for suffix, files := range map[string]map[int]string{outSuffix: stdoutFiles, errSuffix: stderrFiles} {
+		paths, err := filepath.Glob(filepath.Join(dir, prefix+"*"+suffix))
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to find the output files in %s: %w", dir, err)
+		}
+		for _, path := range paths {
+			rank, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), prefix), suffix))
+			if err != nil {
+				continue
+			}
+			files[rank] = path
+		}
+	}
+	return stdoutFiles, stderrFiles, nil
+}
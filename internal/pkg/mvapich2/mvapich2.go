@@ -7,13 +7,13 @@ package mvapich2
 
 import (
 	"fmt"
-	"path/filepath"
+	"strconv"
 	"strings"
 
-	"github.com/gvallee/go_exec/pkg/advexec"
 	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/network"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mapping"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/params"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
-	"github.com/gvallee/go_util/pkg/util"
 )
 
 const (
@@ -32,19 +32,143 @@ const (
 	VersionPrefix1 = "MVAPICH2 Version: "
 
 	VersionPrefix2 = "Version: "
+
+	// VersionBin is the name of the binary used to detect and query MVAPICH2
+	VersionBin = "mpirun_rsh"
+
+	// VersionArg is the argument to pass to VersionBin to get the version of MVAPICH2
+	VersionArg = "-v"
 )
 
-// GetExtraMpirunArgs returns the set of arguments required for the mpirun command for the target platform
-func GetExtraMpirunArgs(sys *sys.Config, netCfg *network.Config, extraArgs []string) []string {
-	extraArgs = append(extraArgs, "-genv")
-	extraArgs = append(extraArgs, "MV2_HOMOGENEOUS_CLUSTER=1")
-	extraArgs = append(extraArgs, "MV2_USE_RDMA_CM=0")
-	extraArgs = append(extraArgs, "MV2_CPU_BINDING_POLICY=hybrid")
-	extraArgs = append(extraArgs, "MV2_HYBRID_BINDING_POLICY=spread")
-	return extraArgs
+// GetParams returns the MVAPICH2 environment variables required for the target platform.
+// The default hybrid binding policy is only used when no mapping spec is provided.
+func GetParams(sys *sys.Config, netCfg *network.Config, spec *mapping.Spec) *params.Set {
+	set := params.New()
+	set.SetDefault(params.KindEnv, "MV2_HOMOGENEOUS_CLUSTER", "1")
+	set.SetDefault(params.KindEnv, "MV2_USE_RDMA_CM", "0")
+	if spec == nil {
+		set.SetDefault(params.KindEnv, "MV2_CPU_BINDING_POLICY", "hybrid")
+		set.SetDefault(params.KindEnv, "MV2_HYBRID_BINDING_POLICY", "spread")
+	}
+	if netCfg != nil {
+		device, port := netCfg.DeviceAndPort()
+		if device != "" {
+			_ = set.SetRequired(params.KindEnv, "MV2_IBA_HCA", device)
+		}
+		if port > 0 {
+			_ = set.SetRequired(params.KindEnv, "MV2_DEFAULT_PORT", strconv.Itoa(port))
+		}
+	}
+	return set
 }
 
-func parseMVAPICH2InfoOutputForVersion(output string) (string, error) {
+// ValidateParams checks that a set of parameters can be used with MVAPICH2
+func ValidateParams(set *params.Set) error {
+	for _, p := range set.Params() {
+		if p.Kind != params.KindEnv {
+			return fmt.Errorf("MVAPICH2 does not support %s parameters (%s)", p.Kind, p.Name)
+		}
+	}
+	return nil
+}
+
+// GetParamArgs translates a set of parameters into mpirun arguments
+func GetParamArgs(set *params.Set) []string {
+	var args []string
+	for _, p := range set.Params() {
+		if p.Kind == params.KindEnv {
+			args = append(args, "-genv", p.Name+"="+p.Value)
+		}
+	}
+	return args
+}
+
+// GetInterfaceArgs returns the mpirun arguments selecting the network interface to use
+func GetInterfaceArgs(netCfg *network.Config) []string {
+	var args []string
+	if netCfg != nil && len(netCfg.TCPInclude) > 0 {
+		args = append(args, "-iface", netCfg.TCPInclude[0])
+	}
+	return args
+}
+
+// GetExtraMpirunArgs returns the set of arguments required for the mpirun command for the target platform,
+// appended to extraArgs
+func GetExtraMpirunArgs(sys *sys.Config, netCfg *network.Config, spec *mapping.Spec, extraArgs []string) []string {
+	extraArgs = append(extraArgs, GetParamArgs(GetParams(sys, netCfg, spec))...)
+	return append(extraArgs, GetInterfaceArgs(netCfg)...)
+}
+
+// ValidateNetwork checks that a network configuration can be used with MVAPICH2
+func ValidateNetwork(netCfg *network.Config) error {
+	err := netCfg.Validate()
+	if err != nil {
+		return err
+	}
+	if netCfg == nil {
+		return nil
+	}
+	if netCfg.UsesUCX() {
+		return fmt.Errorf("MVAPICH2 does not support UCX transports")
+	}
+	if netCfg.UsesOFI() {
+		return fmt.Errorf("MVAPICH2 does not support libfabric providers")
+	}
+	if len(netCfg.TCPInclude) > 1 {
+		return fmt.Errorf("MVAPICH2 only supports a single TCP interface")
+	}
+	if len(netCfg.TCPExclude) > 0 {
+		return fmt.Errorf("MVAPICH2 does not support excluding TCP interfaces")
+	}
+	return nil
+}
+
+// GetMappingArgs translates a mapping/binding spec into mpirun arguments and MVAPICH2 environment variables
+func GetMappingArgs(spec *mapping.Spec) ([]string, error) {
+	var args []string
+	if spec == nil {
+		return args, nil
+	}
+	err := spec.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.RanksPerSocket > 0 || spec.RanksPerNUMA > 0 {
+		return nil, fmt.Errorf("MVAPICH2 only supports a number of ranks per node")
+	}
+	if spec.RanksPerNode > 0 {
+		args = append(args, "-ppn", strconv.Itoa(spec.RanksPerNode))
+	}
+
+	switch spec.BindTo {
+	case "":
+	case mapping.BindNone:
+		args = append(args, "-genv", "MV2_ENABLE_AFFINITY=0")
+	case mapping.BindCore:
+		args = append(args, "-genv", "MV2_CPU_BINDING_LEVEL=core")
+	case mapping.BindSocket:
+		args = append(args, "-genv", "MV2_CPU_BINDING_LEVEL=socket")
+	case mapping.BindNUMA:
+		args = append(args, "-genv", "MV2_CPU_BINDING_LEVEL=numanode")
+	default:
+		return nil, fmt.Errorf("MVAPICH2 does not support binding to %s", spec.BindTo)
+	}
+
+	switch spec.RankBy {
+	case "":
+	case mapping.RankCore, mapping.RankSlot:
+		args = append(args, "-genv", "MV2_CPU_BINDING_POLICY=bunch")
+	case mapping.RankSocket, mapping.RankNUMA:
+		args = append(args, "-genv", "MV2_CPU_BINDING_POLICY=scatter")
+	default:
+		return nil, fmt.Errorf("MVAPICH2 does not support ranking by %s", spec.RankBy)
+	}
+	return args, nil
+}
+
+// ParseVersion extracts the version of MVAPICH2 from the output of 'mpirun_rsh -v'
+func ParseVersion(output string) (string, error) {
 	if output == "" {
 		return "", fmt.Errorf("empty output from version command")
 	}
@@ -57,33 +181,3 @@ func parseMVAPICH2InfoOutputForVersion(output string) (string, error) {
 	version = strings.ReplaceAll(version, " ", "")
 	return version, nil
 }
-
-// DetectFromDir tries to figure out which version of OpenMPI is installed in a given directory
-func DetectFromDir(dir string, env []string) (string, string, error) {
-	targetBin := filepath.Join(dir, "bin", "mpirun_rsh")
-	if !util.FileExists(targetBin) {
-		return "", "", fmt.Errorf("%s does not exist, not an MVAPICH2 implementation", targetBin)
-	}
-
-	var versionCmd advexec.Advcmd
-	versionCmd.BinPath = targetBin
-	versionCmd.CmdArgs = append(versionCmd.CmdArgs, "-v")
-	versionCmd.ExecDir = filepath.Join(dir, "bin")
-	versionCmd.Env = env
-	if env == nil {
-		newLDPath := filepath.Join(dir, "lib") + ":$LD_LIBRARY_PATH"
-		newPath := filepath.Join(dir, "bin") + ":$PATH"
-		versionCmd.Env = append(versionCmd.Env, "LD_LIBRARY_PATH="+newLDPath)
-		versionCmd.Env = append(versionCmd.Env, "PATH="+newPath)
-	}
-	res := versionCmd.Run()
-	if res.Err != nil {
-		return "", "", fmt.Errorf("unable to execute %s: %w", targetBin, res.Err)
-	}
-	version, err := parseMVAPICH2InfoOutputForVersion(res.Stdout)
-	if err != nil {
-		return "", "", fmt.Errorf("parseMVAPICH2InfoOutputForVersion() failed: %w", err)
-	}
-
-	return ID, version, nil
-}
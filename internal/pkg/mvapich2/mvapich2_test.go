@@ -5,7 +5,12 @@
 
 package mvapich2
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mapping"
+)
 
 func TestParseMVAPICH2InfoOutputForVersion(t *testing.T) {
 	output := `MVAPICH2 Version:       2.3.7
@@ -18,11 +23,38 @@ func TestParseMVAPICH2InfoOutputForVersion(t *testing.T) {
 	MVAPICH2 FC:    gfortran`
 	expectedResult := "2.3.7"
 
-	version, err := parseMVAPICH2InfoOutputForVersion(output)
+	version, err := ParseVersion(output)
 	if err != nil {
-		t.Fatalf("parseMVAPICH2InfoOutputForVersion() failed: %s", err)
+		t.Fatalf("ParseVersion() failed: %s", err)
 	}
 	if version != expectedResult {
-		t.Fatalf("parseMVAPICH2InfoOutputForVersion() returned %s instead of %s", version, expectedResult)
+		t.Fatalf("ParseVersion() returned %s instead of %s", version, expectedResult)
+	}
+}
+
+func TestGetMappingArgs(t *testing.T) {
+	spec := &mapping.Spec{RanksPerNode: 4, BindTo: mapping.BindSocket, RankBy: mapping.RankSocket}
+	args, err := GetMappingArgs(spec)
+	if err != nil {
+		t.Fatalf("GetMappingArgs() failed: %s", err)
+	}
+	expected := "-ppn 4 -genv MV2_CPU_BINDING_LEVEL=socket -genv MV2_CPU_BINDING_POLICY=scatter"
+	if strings.Join(args, " ") != expected {
+		t.Fatalf("GetMappingArgs() returned %q instead of %q", strings.Join(args, " "), expected)
+	}
+
+	// The default hybrid binding policy must not conflict with the spec
+	extraArgs := strings.Join(GetExtraMpirunArgs(nil, nil, spec, nil), " ")
+	if strings.Contains(extraArgs, "MV2_CPU_BINDING_POLICY") {
+		t.Fatalf("GetExtraMpirunArgs() returned %q", extraArgs)
+	}
+	extraArgs = strings.Join(GetExtraMpirunArgs(nil, nil, nil, nil), " ")
+	if !strings.Contains(extraArgs, "MV2_CPU_BINDING_POLICY=hybrid") {
+		t.Fatalf("GetExtraMpirunArgs() returned %q", extraArgs)
+	}
+
+	_, err = GetMappingArgs(&mapping.Spec{BindTo: mapping.BindHWThread})
+	if err == nil {
+		t.Fatalf("GetMappingArgs() succeeded with an unsupported binding")
 	}
 }
@@ -0,0 +1,177 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package network
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultSysfsRoot is the default mount point of sysfs
+	DefaultSysfsRoot = "/sys"
+
+	// TypeInfiniBand is the type of InfiniBand devices
+	TypeInfiniBand = "infiniband"
+
+	// TypeRoCE is the type of RDMA devices using Ethernet as link layer
+	TypeRoCE = "roce"
+
+	// TypeEthernet is the type of Ethernet interfaces
+	TypeEthernet = "ethernet"
+
+	// arphrdEther is the ARP hardware type of Ethernet interfaces, as reported in /sys/class/net/<iface>/type
+	arphrdEther = "1"
+)
+
+// Device is a network device that is available on the local node
+type Device struct {
+	// Name is the name of the device, e.g., mlx5_0 or eth0
+	Name string
+
+	// Port is the port of the RDMA device (0 for Ethernet interfaces)
+	Port int
+
+	// Type is the type of the device (TypeInfiniBand, TypeRoCE or TypeEthernet)
+	Type string
+
+	// State is the state of the link as reported by the kernel, e.g., ACTIVE or up
+	State string
+
+	// Up specifies whether the link is up
+	Up bool
+
+	// Rate is the rate of the link as reported by the kernel, e.g., "100 Gb/sec (4X EDR)"
+	Rate string
+
+	// RateMbps is the rate of the link in Mb/s (0 if unknown)
+	RateMbps int
+}
+
+// ID returns the identifier of the device as used in Config.Device, e.g., mlx5_0:1 or eth0
+func (d *Device) ID() string {
+	if d.Port > 0 {
+		return fmt.Sprintf("%s:%d", d.Name, d.Port)
+	}
+	return d.Name
+}
+
+func readSysfsFile(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// parseRDMARate parses the rate of an RDMA port, e.g., "100 Gb/sec (4X EDR)", and returns it in Mb/s
+func parseRDMARate(rate string) int {
+	tokens := strings.Fields(rate)
+	if len(tokens) < 2 {
+		return 0
+	}
+	value, err := strconv.ParseFloat(tokens[0], 64)
+	if err != nil {
+		return 0
+	}
+	switch tokens[1] {
+	case "Gb/sec":
+		return int(value * 1000)
+	case "Mb/sec":
+		return int(value)
+	}
+	return 0
+}
+
+func discoverRDMADevices(sysfsRoot string) ([]Device, error) {
+	var devices []Device
+	ibDir := filepath.Join(sysfsRoot, "class", "infiniband")
+	entries, err := os.ReadDir(ibDir)
+	if os.IsNotExist(err) {
+		return devices, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", ibDir, err)
+	}
+	for _, e := range entries {
+		portsDir := filepath.Join(ibDir, e.Name(), "ports")
+		ports, err := os.ReadDir(portsDir)
+		if err != nil {
+			continue
+		}
+		for _, p := range ports {
+			port, err := strconv.Atoi(p.Name())
+			if err != nil {
+				continue
+			}
+			portDir := filepath.Join(portsDir, p.Name())
+			d := Device{Name: e.Name(), Port: port, Type: TypeInfiniBand}
+			if readSysfsFile(filepath.Join(portDir, "link_layer")) == "Ethernet" {
+				d.Type = TypeRoCE
+			}
+			// The state looks like "4: ACTIVE"
+			d.State = readSysfsFile(filepath.Join(portDir, "state"))
+			if idx := strings.Index(d.State, ":"); idx != -1 {
+				d.State = strings.TrimSpace(d.State[idx+1:])
+			}
+			d.Up = d.State == "ACTIVE"
+			d.Rate = readSysfsFile(filepath.Join(portDir, "rate"))
+			d.RateMbps = parseRDMARate(d.Rate)
+			devices = append(devices, d)
+		}
+	}
+	return devices, nil
+}
+
+func discoverEthernetDevices(sysfsRoot string) ([]Device, error) {
+	var devices []Device
+	netDir := filepath.Join(sysfsRoot, "class", "net")
+	entries, err := os.ReadDir(netDir)
+	if os.IsNotExist(err) {
+		return devices, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", netDir, err)
+	}
+	for _, e := range entries {
+		ifaceDir := filepath.Join(netDir, e.Name())
+		if readSysfsFile(filepath.Join(ifaceDir, "type")) != arphrdEther {
+			continue
+		}
+		d := Device{Name: e.Name(), Type: TypeEthernet}
+		d.State = readSysfsFile(filepath.Join(ifaceDir, "operstate"))
+		d.Up = d.State == "up"
+		// The speed is not readable or -1 when the link is down
+		speed, err := strconv.Atoi(readSysfsFile(filepath.Join(ifaceDir, "speed")))
+		if err == nil && speed > 0 {
+			d.RateMbps = speed
+			d.Rate = fmt.Sprintf("%d Mb/s", speed)
+		}
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+// Discover enumerates the InfiniBand/RoCE devices and ports, and the Ethernet interfaces
+// of the local node, RDMA devices first. sysfsRoot is the mount point of sysfs, DefaultSysfsRoot if empty.
+func Discover(sysfsRoot string) ([]Device, error) {
+	if sysfsRoot == "" {
+		sysfsRoot = DefaultSysfsRoot
+	}
+
+	devices, err := discoverRDMADevices(sysfsRoot)
+	if err != nil {
+		return nil, err
+	}
+	ethDevices, err := discoverEthernetDevices(sysfsRoot)
+	if err != nil {
+		return nil, err
+	}
+	return append(devices, ethDevices...), nil
+}
@@ -1,12 +1,87 @@
-// Copyright (c) 2021, NVIDIA CORPORATION. All rights reserved.
+// Copyright (c) 2021-2025, NVIDIA CORPORATION. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
 
 package network
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // Config is the network configuration to use
 type Config struct {
-	// Device is the network ID to use to run application
+	// Device is the network ID to use to run application, optionally with the port (e.g., mlx5_0:1)
 	Device string
+
+	// HCAPort is the port of the HCA to use when not specified as part of Device (optional)
+	HCAPort int
+
+	// UCXTLS is the list of UCX transports to use (e.g., rc, sm, self)
+	UCXTLS []string
+
+	// OFIProvider is the libfabric provider to use (e.g., verbs, tcp, cxi)
+	OFIProvider string
+
+	// TCPInclude is the list of network interfaces to use for TCP communications
+	TCPInclude []string
+
+	// TCPExclude is the list of network interfaces not to use for TCP communications
+	TCPExclude []string
+}
+
+// Validate checks that the network configuration is consistent, regardless of the MPI implementation
+func (c *Config) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if len(c.TCPInclude) > 0 && len(c.TCPExclude) > 0 {
+		return fmt.Errorf("TCP interfaces cannot be both included and excluded")
+	}
+	if c.HCAPort < 0 {
+		return fmt.Errorf("invalid HCA port: %d", c.HCAPort)
+	}
+	if c.Device != "" && c.HCAPort > 0 && strings.Contains(c.Device, ":") {
+		_, port := c.DeviceAndPort()
+		if port != c.HCAPort {
+			return fmt.Errorf("HCA port %d does not match device %s", c.HCAPort, c.Device)
+		}
+	}
+	return nil
+}
+
+// DeviceAndPort returns the name of the device and the port to use (0 if not specified)
+func (c *Config) DeviceAndPort() (string, int) {
+	tokens := strings.SplitN(c.Device, ":", 2)
+	if len(tokens) == 2 {
+		port, err := strconv.Atoi(tokens[1])
+		if err == nil {
+			return tokens[0], port
+		}
+	}
+	return tokens[0], c.HCAPort
+}
+
+// UCXNetDevices returns the value to use for UCX_NET_DEVICES, e.g., mlx5_0:1
+func (c *Config) UCXNetDevices() string {
+	device, port := c.DeviceAndPort()
+	if device == "" {
+		return ""
+	}
+	if port > 0 {
+		return fmt.Sprintf("%s:%d", device, port)
+	}
+	return device
+}
+
+// UsesUCX checks whether the configuration includes UCX-specific settings
+func (c *Config) UsesUCX() bool {
+	return c != nil && len(c.UCXTLS) > 0
+}
+
+// UsesOFI checks whether the configuration includes libfabric-specific settings
+func (c *Config) UsesOFI() bool {
+	return c != nil && c.OFIProvider != ""
 }
@@ -0,0 +1,111 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUCXNetDevices(t *testing.T) {
+	tests := []struct {
+		cfg      Config
+		expected string
+	}{
+		{cfg: Config{Device: "mlx5_0:1"}, expected: "mlx5_0:1"},
+		{cfg: Config{Device: "mlx5_0", HCAPort: 2}, expected: "mlx5_0:2"},
+		{cfg: Config{Device: "mlx5_0"}, expected: "mlx5_0"},
+		{cfg: Config{HCAPort: 1}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		if r := tt.cfg.UCXNetDevices(); r != tt.expected {
+			t.Fatalf("UCXNetDevices() returned %q instead of %q for %+v", r, tt.expected, tt.cfg)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := []Config{
+		{TCPInclude: []string{"eth0"}, TCPExclude: []string{"lo"}},
+		{HCAPort: -1},
+		{Device: "mlx5_0:1", HCAPort: 2},
+	}
+	for _, cfg := range invalid {
+		err := cfg.Validate()
+		if err == nil {
+			t.Fatalf("Validate() succeeded for %+v", cfg)
+		}
+	}
+
+	var cfg *Config
+	err := cfg.Validate()
+	if err != nil {
+		t.Fatalf("Validate() failed on an undefined configuration: %s", err)
+	}
+}
+
+func writeSysfsFile(t *testing.T, path string, content string) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatalf("unable to create %s: %s", filepath.Dir(path), err)
+	}
+	err = os.WriteFile(path, []byte(content+"\n"), 0644)
+	if err != nil {
+		t.Fatalf("unable to create %s: %s", path, err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	port := filepath.Join(root, "class", "infiniband", "mlx5_0", "ports", "1")
+	writeSysfsFile(t, filepath.Join(port, "state"), "4: ACTIVE")
+	writeSysfsFile(t, filepath.Join(port, "rate"), "100 Gb/sec (4X EDR)")
+	writeSysfsFile(t, filepath.Join(port, "link_layer"), "InfiniBand")
+	port = filepath.Join(root, "class", "infiniband", "mlx5_1", "ports", "1")
+	writeSysfsFile(t, filepath.Join(port, "state"), "1: DOWN")
+	writeSysfsFile(t, filepath.Join(port, "rate"), "25 Gb/sec (1X EDR)")
+	writeSysfsFile(t, filepath.Join(port, "link_layer"), "Ethernet")
+	iface := filepath.Join(root, "class", "net", "eth0")
+	writeSysfsFile(t, filepath.Join(iface, "type"), "1")
+	writeSysfsFile(t, filepath.Join(iface, "operstate"), "up")
+	writeSysfsFile(t, filepath.Join(iface, "speed"), "25000")
+	iface = filepath.Join(root, "class", "net", "eth1")
+	writeSysfsFile(t, filepath.Join(iface, "type"), "1")
+	writeSysfsFile(t, filepath.Join(iface, "operstate"), "down")
+	writeSysfsFile(t, filepath.Join(iface, "speed"), "-1")
+	iface = filepath.Join(root, "class", "net", "lo")
+	writeSysfsFile(t, filepath.Join(iface, "type"), "772")
+	writeSysfsFile(t, filepath.Join(iface, "operstate"), "unknown")
+
+	devices, err := Discover(root)
+	if err != nil {
+		t.Fatalf("Discover() failed: %s", err)
+	}
+	expected := []Device{
+		{Name: "mlx5_0", Port: 1, Type: TypeInfiniBand, State: "ACTIVE", Up: true, Rate: "100 Gb/sec (4X EDR)", RateMbps: 100000},
+		{Name: "mlx5_1", Port: 1, Type: TypeRoCE, State: "DOWN", Up: false, Rate: "25 Gb/sec (1X EDR)", RateMbps: 25000},
+		{Name: "eth0", Type: TypeEthernet, State: "up", Up: true, Rate: "25000 Mb/s", RateMbps: 25000},
+		{Name: "eth1", Type: TypeEthernet, State: "down", Up: false},
+	}
+	if len(devices) != len(expected) {
+		t.Fatalf("Discover() returned %d devices instead of %d: %+v", len(devices), len(expected), devices)
+	}
+	for idx := range expected {
+		if devices[idx] != expected[idx] {
+			t.Fatalf("Discover() returned %+v instead of %+v", devices[idx], expected[idx])
+		}
+	}
+	if devices[0].ID() != "mlx5_0:1" || devices[2].ID() != "eth0" {
+		t.Fatalf("invalid device IDs: %s %s", devices[0].ID(), devices[2].ID())
+	}
+
+	devices, err = Discover(t.TempDir())
+	if err != nil || len(devices) != 0 {
+		t.Fatalf("Discover() returned %v, %v on an empty tree", devices, err)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package openmpi
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// mcaPrefix is the prefix of the lines describing MCA components in the output of 'ompi_info --parsable'
+	mcaPrefix = "mca:"
+
+	// cudaParamSuffix is the suffix of the parameter specifying whether Open MPI was built with CUDA support
+	cudaParamSuffix = ":param:mpi_built_with_cuda_support:value:true"
+)
+
+// CapabilitiesArgs are the arguments to pass to VersionBin to get the capabilities of an Open MPI build
+var CapabilitiesArgs = []string{"--parsable", "--all"}
+
+// Capabilities describes what a specific build of Open MPI supports
+type Capabilities struct {
+	// Version is the full version of Open MPI
+	Version string
+
+	// Components is the list of MCA components that are available, per framework (e.g., "pml" -> ["ob1", "ucx"])
+	Components map[string][]string
+
+	// UCX specifies whether the UCX PML is available
+	UCX bool
+
+	// CUDA specifies whether Open MPI was built with CUDA support
+	CUDA bool
+
+	// PMIxVersion is the version of PMIx used by Open MPI, when it can be figured out
+	PMIxVersion string
+
+	// Slurm specifies whether Open MPI was built with Slurm support
+	Slurm bool
+}
+
+// HasComponent checks whether a given MCA component of a framework is available
+func (c *Capabilities) HasComponent(framework string, component string) bool {
+	for _, comp := range c.Components[framework] {
+		if comp == component {
+			return true
+		}
+	}
+	return false
+}
+
+// pmixVersionFromComponent figures out the major version of PMIx from the name of the
+// PMIx component embedded in Open MPI, e.g., "pmix3x" or "ext3x" for PMIx 3
+func pmixVersionFromComponent(name string) string {
+	for _, prefix := range []string{"pmix", "ext"} {
+		if strings.HasPrefix(name, prefix) && strings.HasSuffix(name, "x") {
+			v := strings.TrimSuffix(strings.TrimPrefix(name, prefix), "x")
+			if v != "" && strings.Trim(v, "0123456789") == "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// ParseCapabilities parses the output of 'ompi_info --parsable --all'
+func ParseCapabilities(output string) (*Capabilities, error) {
+	c := new(Capabilities)
+	c.Components = make(map[string][]string)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "ompi:version:full:"):
+			c.Version = strings.TrimPrefix(line, "ompi:version:full:")
+		case strings.HasPrefix(line, "pmix:version:full:"):
+			c.PMIxVersion = strings.TrimPrefix(line, "pmix:version:full:")
+		case strings.HasPrefix(line, mcaPrefix):
+			if strings.HasSuffix(line, cudaParamSuffix) {
+				c.CUDA = true
+			}
+			// Component lines look like "mca:pml:ucx:version:component:4.1.5"
+			tokens := strings.Split(line, ":")
+			if len(tokens) < 5 || tokens[3] != "version" || tokens[4] != "component" {
+				continue
+			}
+			framework := tokens[1]
+			component := tokens[2]
+			if !c.HasComponent(framework, component) {
+				c.Components[framework] = append(c.Components[framework], component)
+			}
+		}
+	}
+
+	if c.Version == "" && len(c.Components) == 0 {
+		return nil, fmt.Errorf("invalid output format")
+	}
+
+	c.UCX = c.HasComponent("pml", "ucx")
+	if c.HasComponent("accelerator", "cuda") {
+		c.CUDA = true
+	}
+	for _, framework := range []string{"plm", "ras", "ess", "schizo"} {
+		if c.HasComponent(framework, "slurm") {
+			c.Slurm = true
+		}
+	}
+	if c.PMIxVersion == "" {
+		for _, comp := range c.Components["pmix"] {
+			v := pmixVersionFromComponent(comp)
+			if v != "" {
+				c.PMIxVersion = v
+				break
+			}
+		}
+	}
+
+	return c, nil
+}
@@ -8,14 +8,13 @@ package openmpi
 
 import (
 	"fmt"
-	"log"
-	"path/filepath"
 	"strings"
 
-	"github.com/gvallee/go_exec/pkg/advexec"
 	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/network"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mapping"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/nodelist"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/params"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
-	"github.com/gvallee/go_util/pkg/util"
 )
 
 const (
@@ -30,24 +29,194 @@ const (
 
 	// ID is the internal ID for Open MPI
 	ID = "openmpi"
+
+	// VersionBin is the name of the binary used to detect and query Open MPI
+	VersionBin = "ompi_info"
+
+	// VersionArg is the argument to pass to VersionBin to get the version of Open MPI
+	VersionArg = "--version"
 )
 
-// GetExtraMpirunArgs returns the set of arguments required for the mpirun command for the target platform
-func GetExtraMpirunArgs(sys *sys.Config, netCfg *network.Config, extraArgs []string) []string {
-	// By default we always prefer UCX rather than openib
-	extraArgs = append(extraArgs, "--mca")
-	extraArgs = append(extraArgs, "btl")
-	extraArgs = append(extraArgs, "^openib")
-	extraArgs = append(extraArgs, "--mca")
-	extraArgs = append(extraArgs, "pml")
-	extraArgs = append(extraArgs, "ucx")
-	if netCfg != nil && netCfg.Device != "" {
-		extraArgs = append(extraArgs, "-x UCX_NET_DEVICES="+netCfg.Device)
-	}
-	return extraArgs
+// GetParams returns the MCA parameters and environment variables required for the target platform.
+// caps is optional; when known, the capabilities of the Open MPI build are used to select compatible
+// components, otherwise UCX is assumed to be available. Parameters derived from an explicit network
+// configuration are required, the others can be overridden by the user.
+func GetParams(sys *sys.Config, netCfg *network.Config, caps *Capabilities) *params.Set {
+	set := params.New()
+	switch {
+	case netCfg.UsesOFI():
+		_ = set.SetRequired(params.KindMCA, "pml", "cm")
+		_ = set.SetRequired(params.KindMCA, "mtl", "ofi")
+		_ = set.SetRequired(params.KindMCA, "mtl_ofi_provider_include", netCfg.OFIProvider)
+		_ = set.SetRequired(params.KindEnv, "FI_PROVIDER", netCfg.OFIProvider)
+	case caps != nil && !caps.UCX:
+		// UCX is not built in, fall back to ob1
+		set.SetDefault(params.KindMCA, "pml", "ob1")
+	default:
+		// By default we always prefer UCX rather than openib
+		set.SetDefault(params.KindMCA, "btl", "^openib")
+		if netCfg.UsesUCX() || (netCfg != nil && netCfg.Device != "") {
+			_ = set.SetRequired(params.KindMCA, "pml", "ucx")
+		} else {
+			set.SetDefault(params.KindMCA, "pml", "ucx")
+		}
+		if netCfg != nil && netCfg.Device != "" {
+			_ = set.SetRequired(params.KindEnv, "UCX_NET_DEVICES", netCfg.UCXNetDevices())
+		}
+		if netCfg.UsesUCX() {
+			_ = set.SetRequired(params.KindEnv, "UCX_TLS", strings.Join(netCfg.UCXTLS, ","))
+		}
+	}
+
+	if netCfg != nil && len(netCfg.TCPInclude) > 0 {
+		_ = set.SetRequired(params.KindMCA, "btl_tcp_if_include", strings.Join(netCfg.TCPInclude, ","))
+	}
+	if netCfg != nil && len(netCfg.TCPExclude) > 0 {
+		_ = set.SetRequired(params.KindMCA, "btl_tcp_if_exclude", strings.Join(netCfg.TCPExclude, ","))
+	}
+	return set
+}
+
+// GetParamArgs translates a set of parameters into mpirun arguments
+func GetParamArgs(set *params.Set) []string {
+	var args []string
+	for _, p := range set.Params() {
+		switch p.Kind {
+		case params.KindMCA:
+			args = append(args, "--mca", p.Name, p.Value)
+		case params.KindEnv:
+			args = append(args, "-x", p.Name+"="+p.Value)
+		}
+	}
+	return args
+}
+
+// GetEnvForwardArgs returns the mpirun arguments forwarding to the ranks the environment variables of a set
+// of parameters that are set in the environment of mpirun (-x NAME). MCA parameters do not need to be
+// forwarded, mpirun forwards the OMPI_MCA_ variables of its environment.
+func GetEnvForwardArgs(set *params.Set) []string {
+	var args []string
+	for _, p := range set.Params() {
+		if p.Kind == params.KindEnv {
+			args = append(args, "-x", p.Name)
+		}
+	}
+	return args
+}
+
+// ParseParamArgs extracts the MCA parameters (--mca name value) and environment variables
+// (-x NAME=VALUE) from a list of mpirun arguments. The other arguments are returned as they are.
+func ParseParamArgs(args []string) (*params.Set, []string, error) {
+	set := params.New()
+	var others []string
+	for idx := 0; idx < len(args); idx++ {
+		switch {
+		case args[idx] == "--mca" || args[idx] == "-mca":
+			if idx+2 >= len(args) {
+				return nil, nil, fmt.Errorf("missing name or value for %s", args[idx])
+			}
+			err := set.SetUser(params.KindMCA, args[idx+1], args[idx+2])
+			if err != nil {
+				return nil, nil, err
+			}
+			idx += 2
+		case args[idx] == "-x" && idx+1 < len(args) && strings.Contains(args[idx+1], "="):
+			p, err := params.Parse(params.KindEnv, args[idx+1])
+			if err != nil {
+				return nil, nil, err
+			}
+			err = set.Add(p)
+			if err != nil {
+				return nil, nil, err
+			}
+			idx++
+		default:
+			// Includes "-x NAME", which forwards a variable from the environment
+			others = append(others, args[idx])
+		}
+	}
+	return set, others, nil
+}
+
+// GetExtraMpirunArgs returns the set of arguments required for the mpirun command for the target platform,
+// appended to extraArgs. See GetParams for details about caps.
+func GetExtraMpirunArgs(sys *sys.Config, netCfg *network.Config, caps *Capabilities, extraArgs []string) []string {
+	return append(extraArgs, GetParamArgs(GetParams(sys, netCfg, caps))...)
+}
+
+// ValidateNetwork checks that a network configuration can be used with a build of Open MPI.
+// caps is optional; when unknown, only the consistency of the configuration is checked.
+func ValidateNetwork(netCfg *network.Config, caps *Capabilities) error {
+	err := netCfg.Validate()
+	if err != nil {
+		return err
+	}
+	if netCfg == nil {
+		return nil
+	}
+	if netCfg.UsesUCX() && netCfg.UsesOFI() {
+		return fmt.Errorf("UCX transports and a libfabric provider cannot be used at the same time")
+	}
+	if caps == nil {
+		return nil
+	}
+	if (netCfg.UsesUCX() || (netCfg.Device != "" && !netCfg.UsesOFI())) && !caps.UCX {
+		return fmt.Errorf("Open MPI was built without UCX support")
+	}
+	if netCfg.UsesOFI() && !caps.HasComponent("mtl", "ofi") {
+		return fmt.Errorf("Open MPI was built without libfabric support")
+	}
+	return nil
+}
+
+// GetMappingArgs translates a mapping/binding spec into mpirun arguments
+func GetMappingArgs(spec *mapping.Spec) ([]string, error) {
+	var args []string
+	if spec == nil {
+		return args, nil
+	}
+	err := spec.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case spec.RanksPerNode > 0:
+		args = append(args, "--map-by", fmt.Sprintf("ppr:%d:node", spec.RanksPerNode))
+	case spec.RanksPerSocket > 0:
+		args = append(args, "--map-by", fmt.Sprintf("ppr:%d:socket", spec.RanksPerSocket))
+	case spec.RanksPerNUMA > 0:
+		args = append(args, "--map-by", fmt.Sprintf("ppr:%d:numa", spec.RanksPerNUMA))
+	}
+	if spec.RankBy != "" {
+		args = append(args, "--rank-by", spec.RankBy)
+	}
+	if spec.BindTo != "" {
+		args = append(args, "--bind-to", spec.BindTo)
+	}
+	return args, nil
+}
+
+// GetHostfileContent returns the content of an Open MPI hostfile for a list of nodes
+func GetHostfileContent(nodes []nodelist.Node) string {
+	content := ""
+	for _, n := range nodes {
+		if n.Slots > 0 {
+			content += fmt.Sprintf("%s slots=%d\n", n.Name, n.Slots)
+		} else {
+			content += n.Name + "\n"
+		}
+	}
+	return content
 }
 
-func parseOmpiInfoOutputForVersion(output string) (string, error) {
+// GetHostfileArgs returns the mpirun arguments to use a given hostfile
+func GetHostfileArgs(path string) []string {
+	return []string{"--hostfile", path}
+}
+
+// ParseVersion extracts the version of Open MPI from the output of 'ompi_info --version'
+func ParseVersion(output string) (string, error) {
 	lines := strings.Split(output, "\n")
 	if !strings.HasPrefix(lines[0], "Open MPI") {
 		return "", fmt.Errorf("invalid output format")
@@ -57,42 +226,8 @@ func parseOmpiInfoOutputForVersion(output string) (string, error) {
 	return version, nil
 }
 
-// DetectFromDir tries to figure out which version of OpenMPI is installed in a given directory
-func DetectFromDir(dir string, env []string) (string, string, error) {
-	targetBin := filepath.Join(dir, "bin", "ompi_info")
-	if !util.FileExists(targetBin) {
-		return "", "", fmt.Errorf("%s does not exist, not an OpenMPI implementation", targetBin)
-	}
-
-	var versionCmd advexec.Advcmd
-	versionCmd.BinPath = targetBin
-	versionCmd.CmdArgs = append(versionCmd.CmdArgs, "--version")
-	versionCmd.ExecDir = filepath.Join(dir, "bin")
-	versionCmd.Env = env
-	if env == nil {
-		newLDPath := filepath.Join(dir, "lib") + ":$LD_LIBRARY_PATH"
-		newPath := filepath.Join(dir, "bin") + ":$PATH"
-		versionCmd.Env = append(versionCmd.Env, "LD_LIBRARY_PATH="+newLDPath)
-		versionCmd.Env = append(versionCmd.Env, "PATH="+newPath)
-	}
-	res := versionCmd.Run()
-	if res.Err != nil {
-		// If it fails we try with OPAL_PREFIX set. We create a new command to avoid "exec: already started" issues.
-		var versionCmdWithOpalPrefix advexec.Advcmd
-		versionCmdWithOpalPrefix.BinPath = versionCmd.BinPath
-		versionCmdWithOpalPrefix.CmdArgs = versionCmd.CmdArgs
-		versionCmdWithOpalPrefix.Env = versionCmd.Env
-		versionCmdWithOpalPrefix.Env = append(versionCmd.Env, "OPAL_PREFIX="+dir)
-		res = versionCmdWithOpalPrefix.Run()
-		if res.Err != nil {
-			log.Printf("unable to run ompi_info: %s; stdout: %s; stderr: %s", res.Err, res.Stdout, res.Stderr)
-			return "", "", res.Err
-		}
-	}
-	version, err := parseOmpiInfoOutputForVersion(res.Stdout)
-	if err != nil {
-		return "", "", fmt.Errorf("parseOmpiInfoOutputForVersion() failed - %w", err)
-	}
-
-	return ID, version, nil
+// RetryEnv returns the extra environment required to run Open MPI tools when the
+// installation has been moved from its original prefix
+func RetryEnv(dir string) []string {
+	return []string{"OPAL_PREFIX=" + dir}
 }
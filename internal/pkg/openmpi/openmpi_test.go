@@ -5,17 +5,133 @@
 
 package openmpi
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/network"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mapping"
+)
 
 func TestParseOMPIInfoOutputForVersion(t *testing.T) {
 	output := "Open MPI v3.0.4\n\nhttp://www.open-mpi.org/community/help/\n"
 	expectedResult := "3.0.4"
 
-	version, err := parseOmpiInfoOutputForVersion(output)
+	version, err := ParseVersion(output)
 	if err != nil {
-		t.Fatalf("parseOmpiInfoOutputForVersion() failed: %s", err)
+		t.Fatalf("ParseVersion() failed: %s", err)
 	}
 	if version != expectedResult {
-		t.Fatalf("parseOmpiInfoOutputForVersion() returned %s instead of %s", version, expectedResult)
+		t.Fatalf("ParseVersion() returned %s instead of %s", version, expectedResult)
+	}
+}
+
+const ompiInfoParsableOutput = `package:Open MPI builder@node01 Distribution
+ompi:version:full:4.1.5
+ompi:version:repo:v4.1.5
+mpi-api:version:full:3.1.0
+path:prefix:/opt/openmpi-4.1.5
+mca:btl:self:version:mca:2.1.0
+mca:btl:self:version:component:4.1.5
+mca:btl:tcp:version:component:4.1.5
+mca:pml:ob1:version:component:4.1.5
+mca:pml:ucx:version:mca:2.1.0
+mca:pml:ucx:version:api:2.0.0
+mca:pml:ucx:version:component:4.1.5
+mca:pml:ucx:param:pml_ucx_verbose:value:0
+mca:pmix:pmix3x:version:component:4.1.5
+mca:plm:slurm:version:component:4.1.5
+mca:mpi:base:param:mpi_built_with_cuda_support:value:true
+`
+
+func TestParseCapabilities(t *testing.T) {
+	caps, err := ParseCapabilities(ompiInfoParsableOutput)
+	if err != nil {
+		t.Fatalf("ParseCapabilities() failed: %s", err)
+	}
+	if caps.Version != "4.1.5" {
+		t.Fatalf("invalid version: %s", caps.Version)
+	}
+	if !caps.UCX || !caps.CUDA || !caps.Slurm {
+		t.Fatalf("invalid capabilities: %+v", caps)
+	}
+	if caps.PMIxVersion != "3" {
+		t.Fatalf("invalid PMIx version: %s", caps.PMIxVersion)
+	}
+	if !caps.HasComponent("btl", "tcp") || caps.HasComponent("btl", "openib") {
+		t.Fatalf("invalid list of components: %v", caps.Components)
+	}
+	if len(caps.Components["pml"]) != 2 {
+		t.Fatalf("invalid list of PML components: %v", caps.Components["pml"])
+	}
+
+	_, err = ParseCapabilities("Open MPI v4.1.5\n")
+	if err == nil {
+		t.Fatalf("ParseCapabilities() succeeded with an invalid output")
+	}
+}
+
+func TestGetExtraMpirunArgsWithoutUCX(t *testing.T) {
+	caps := &Capabilities{Components: map[string][]string{"pml": {"ob1"}}}
+	args := strings.Join(GetExtraMpirunArgs(nil, nil, caps, nil), " ")
+	if args != "--mca pml ob1" {
+		t.Fatalf("GetExtraMpirunArgs() returned %q", args)
+	}
+
+	caps.UCX = true
+	args = strings.Join(GetExtraMpirunArgs(nil, nil, caps, nil), " ")
+	if !strings.Contains(args, "--mca pml ucx") {
+		t.Fatalf("GetExtraMpirunArgs() returned %q", args)
+	}
+}
+
+func TestGetMappingArgs(t *testing.T) {
+	spec := &mapping.Spec{RanksPerSocket: 2, BindTo: mapping.BindHWThread, RankBy: mapping.RankSlot}
+	args, err := GetMappingArgs(spec)
+	if err != nil {
+		t.Fatalf("GetMappingArgs() failed: %s", err)
+	}
+	expected := "--map-by ppr:2:socket --rank-by slot --bind-to hwthread"
+	if strings.Join(args, " ") != expected {
+		t.Fatalf("GetMappingArgs() returned %q instead of %q", strings.Join(args, " "), expected)
+	}
+
+	args, err = GetMappingArgs(nil)
+	if err != nil || len(args) != 0 {
+		t.Fatalf("GetMappingArgs() returned %v, %v without spec", args, err)
+	}
+}
+
+func TestNetworkArgs(t *testing.T) {
+	netCfg := &network.Config{Device: "mlx5_0", HCAPort: 1, UCXTLS: []string{"rc", "sm"}, TCPExclude: []string{"lo", "docker0"}}
+	args := strings.Join(GetExtraMpirunArgs(nil, netCfg, nil, nil), " ")
+	expected := "--mca btl ^openib --mca pml ucx -x UCX_NET_DEVICES=mlx5_0:1 -x UCX_TLS=rc,sm --mca btl_tcp_if_exclude lo,docker0"
+	if args != expected {
+		t.Fatalf("GetExtraMpirunArgs() returned %q instead of %q", args, expected)
+	}
+
+	netCfg = &network.Config{OFIProvider: "verbs"}
+	args = strings.Join(GetExtraMpirunArgs(nil, netCfg, nil, nil), " ")
+	expected = "--mca pml cm --mca mtl ofi --mca mtl_ofi_provider_include verbs -x FI_PROVIDER=verbs"
+	if args != expected {
+		t.Fatalf("GetExtraMpirunArgs() returned %q instead of %q", args, expected)
+	}
+
+	caps, err := ParseCapabilities(ompiInfoParsableOutput)
+	if err != nil {
+		t.Fatalf("ParseCapabilities() failed: %s", err)
+	}
+	err = ValidateNetwork(netCfg, caps)
+	if err == nil {
+		t.Fatalf("ValidateNetwork() succeeded with a libfabric provider but no OFI MTL")
+	}
+	caps.UCX = false
+	err = ValidateNetwork(&network.Config{UCXTLS: []string{"rc"}}, caps)
+	if err == nil {
+		t.Fatalf("ValidateNetwork() succeeded with UCX transports but no UCX support")
+	}
+	err = ValidateNetwork(&network.Config{TCPInclude: []string{"eth0"}}, caps)
+	if err != nil {
+		t.Fatalf("ValidateNetwork() failed: %s", err)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package openmpi
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TaggedOutputRegexp matches a line of output tagged with --tag-output, e.g., "[1,0]<stdout>:hello";
+// Open MPI 5 adds a space after the tag
+var TaggedOutputRegexp = regexp.MustCompile(`^\[[^,\]]+,(\d+)\]<std(?:out|err)>: ?(.*)$`)
+
+// GetRankOutputArgs returns the mpirun arguments to keep the output of each rank separate: each rank writes
+// to its own files in dir with --output-filename or, if dir is empty, the output is tagged with the rank
+func GetRankOutputArgs(dir string) []string {
+	if dir == "" {
+		return []string{"--tag-output"}
+	}
+	return []string{"--output-filename", dir}
+}
+
+// GetRankOutputFiles returns the paths to the files with stdout and stderr of each rank, written by mpirun
+// with --output-filename in dir, i.e., dir/<job>/rank.<rank>/stdout and dir/<job>/rank.<rank>/stderr
+func GetRankOutputFiles(dir string) (map[int]string, map[int]string, error) {
+	stdoutFiles := make(map[int]string)
+	stderrFiles := make(map[int]string)
+	for stream, files := range map[string]map[int]string{"stdout": stdoutFiles, "stderr": stderrFiles} {
+		paths, err := filepath.Glob(filepath.Join(dir, "*", "rank.*", stream))
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to find the output files in %s: %w", dir, err)
+		}
+		for _, path := range paths {
+			rank, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(path)), "rank."))
+			if err != nil {
+				continue
+			}
+			files[rank] = path
+		}
+	}
+	return stdoutFiles, stderrFiles, nil
+}
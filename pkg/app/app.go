@@ -18,4 +18,48 @@ type Info struct {
 
 	// BinArgs is the list of argument that the application's binary needs
 	BinArgs []string
+
+	// ExpectedOutputs is the list of outputs the application is expected to produce (optional)
+	ExpectedOutputs []ExpectedOutput
+
+	// ExpectedExitCode is the exit code the application is expected to return
+	ExpectedExitCode int
+}
+
+const (
+	// MatchSubstring specifies that the expected output is a substring of the output (default)
+	MatchSubstring = "substring"
+
+	// MatchRegexp specifies that the expected output is a regular expression, in multi-line mode
+	MatchRegexp = "regexp"
+
+	// StreamAny specifies that the expected output can be on stdout or stderr (default)
+	StreamAny = ""
+
+	// StreamStdout specifies that the expected output must be on stdout
+	StreamStdout = "stdout"
+
+	// StreamStderr specifies that the expected output must be on stderr
+	StreamStderr = "stderr"
+
+	// NPTag is replaced by the number of ranks in expected outputs
+	NPTag = "#NP"
+
+	// RankTag is replaced by the rank in expected outputs that are checked per rank
+	RankTag = "#RANK"
+)
+
+// ExpectedOutput is an output that the application is expected to produce
+type ExpectedOutput struct {
+	// Pattern is the expected output, where NPTag and RankTag are substituted
+	Pattern string
+
+	// Match specifies how the pattern is matched (MatchSubstring or MatchRegexp)
+	Match string
+
+	// Stream specifies where the output is expected (StreamAny, StreamStdout or StreamStderr)
+	Stream string
+
+	// PerRank specifies that the output is expected from every rank, with RankTag replaced by the rank
+	PerRank bool
 }
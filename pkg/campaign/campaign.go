@@ -0,0 +1,250 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package campaign runs the same application across a matrix of MPI installations, numbers of ranks,
+// numbers of nodes and environment variants, to compare them.
+package campaign
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/gvallee/go_exec/pkg/advexec"
+	"github.com/gvallee/go_exec/pkg/results"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/app"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/jm"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/launcher"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mpi"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
+)
+
+// EnvVariant is a set of environment variables to run the application with
+type EnvVariant struct {
+	// Name is the name of the variant, used in the summary
+	Name string
+
+	// Env is the set of environment variables of the variant, added to the environment of the job
+	Env map[string]string
+}
+
+// Matrix is the set of values to combine; every combination is run once
+type Matrix struct {
+	// MPIDirs is the list of directories where MPI is installed
+	MPIDirs []string
+
+	// NP is the list of numbers of ranks
+	NP []int
+
+	// NNodes is the list of numbers of nodes (optional, the default of the launcher is used if not set)
+	NNodes []int
+
+	// EnvVariants is the list of environment variants (optional)
+	EnvVariants []EnvVariant
+}
+
+// Combination is a single combination of the matrix
+type Combination struct {
+	// MPIDir is the directory where MPI is installed
+	MPIDir string
+
+	// NP is the number of ranks
+	NP int
+
+	// NNodes is the number of nodes (0 if not specified)
+	NNodes int
+
+	// Env is the environment variant
+	Env EnvVariant
+}
+
+// Config is the configuration of a campaign
+type Config struct {
+	// Name is the name of the campaign, used to name the jobs
+	Name string
+
+	// App is the application to run
+	App app.Info
+
+	// Matrix is the set of values to combine
+	Matrix Matrix
+
+	// MaxConcurrentJobs is the maximum number of jobs running at the same time (1 if not set)
+	MaxConcurrentJobs int
+
+	// PrepareJob is called for each job before it is submitted, to customize it (e.g., partition, time limit) (optional)
+	PrepareJob func(j *job.Job, c *Combination)
+}
+
+// Run is the result of a combination of the campaign
+type Run struct {
+	Combination
+
+	// MPI is the MPI implementation detected in the MPI directory
+	MPI implem.Info
+
+	// JobResult is the structured result of the job
+	JobResult *job.Result
+
+	// Result is the result of the validation of the execution
+	Result results.Result
+
+	// Exec is the result of the submission of the job
+	Exec advexec.Result
+
+	// Err is the error that prevented the job from being submitted, if any
+	Err error
+}
+
+// Combinations returns all the combinations of the matrix, in a deterministic order
+func (m *Matrix) Combinations() []Combination {
+	var combinations []Combination
+	nnodes := m.NNodes
+	if len(nnodes) == 0 {
+		nnodes = []int{0}
+	}
+	variants := m.EnvVariants
+	if len(variants) == 0 {
+		variants = []EnvVariant{{}}
+	}
+	for _, dir := range m.MPIDirs {
+		for _, np := range m.NP {
+			for _, n := range nnodes {
+				for _, v := range variants {
+					combinations = append(combinations, Combination{MPIDir: dir, NP: np, NNodes: n, Env: v})
+				}
+			}
+		}
+	}
+	return combinations
+}
+
+func (cfg *Config) newJob(c *Combination, idx int) *job.Job {
+	j := new(job.Job)
+	j.Name = cfg.Name + "-" + strconv.Itoa(idx)
+	j.App = cfg.App
+	j.NP = c.NP
+	j.NNodes = c.NNodes
+	if len(c.Env.Env) > 0 {
+		j.CustomEnv = make(map[string]string)
+		for k, v := range c.Env.Env {
+			j.CustomEnv[k] = v
+		}
+	}
+	if cfg.PrepareJob != nil {
+		cfg.PrepareJob(j, c)
+	}
+	return j
+}
+
+func runCombination(cfg *Config, idx int, c *Combination, mpiInfo *implem.Info, jobmgr *jm.JM, sysCfg *sys.Config) Run {
+	r := Run{Combination: *c, MPI: *mpiInfo}
+	j := cfg.newJob(c, idx)
+	hostMPI := &mpi.Config{Implem: *mpiInfo}
+	// Arguments set by PrepareJob are passed to the launcher, which otherwise applies its defaults
+	args := j.Args
+	j.Args = nil
+	r.JobResult, r.Exec = launcher.RunWithResult(j, hostMPI, jobmgr, sysCfg, args)
+	r.Result = launcher.CheckResult(j, r.JobResult, &r.Exec)
+	if j.CleanUp != nil {
+		err := j.CleanUp()
+		if err != nil {
+			r.Err = fmt.Errorf("unable to clean up job %s: %w", j.Name, err)
+		}
+	}
+	return r
+}
+
+// Execute runs all the combinations of a campaign through a job manager, with at most
+// cfg.MaxConcurrentJobs jobs at the same time, and returns the runs in the order of the combinations.
+// Combinations with an MPI directory where MPI cannot be detected are reported with an error.
+func Execute(cfg *Config, jobmgr *jm.JM, sysCfg *sys.Config) ([]Run, error) {
+	if len(cfg.Matrix.MPIDirs) == 0 || len(cfg.Matrix.NP) == 0 {
+		return nil, fmt.Errorf("the matrix requires at least one MPI directory and one number of ranks")
+	}
+
+	mpiInfos := make(map[string]implem.Info)
+	mpiErrs := make(map[string]error)
+	for _, dir := range cfg.Matrix.MPIDirs {
+		info, err := mpi.DetectFromDir(dir)
+		if err != nil {
+			mpiErrs[dir] = fmt.Errorf("unable to detect MPI in %s: %w", dir, err)
+			continue
+		}
+		mpiInfos[dir] = info
+	}
+
+	maxJobs := cfg.MaxConcurrentJobs
+	if maxJobs <= 0 {
+		maxJobs = 1
+	}
+	sem := make(chan struct{}, maxJobs)
+	combinations := cfg.Matrix.Combinations()
+	runs := make([]Run, len(combinations))
+	var wg sync.WaitGroup
+	for idx := range combinations {
+		c := &combinations[idx]
+		if err, ok := mpiErrs[c.MPIDir]; ok {
+			runs[idx] = Run{Combination: *c, Err: err}
+			continue
+		}
+		mpiInfo := mpiInfos[c.MPIDir]
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			runs[idx] = runCombination(cfg, idx, c, &mpiInfo, jobmgr, sysCfg)
+			<-sem
+		}(idx)
+	}
+	wg.Wait()
+	return runs, nil
+}
+
+// Summary returns a table summarizing the runs of a campaign
+func Summary(runs []Run) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MPI\tNP\tNODES\tENV\tSTATE\tEXIT\tRESULT\tFAILURE")
+	for _, r := range runs {
+		mpiStr := r.MPIDir
+		if r.MPI.ID != "" {
+			mpiStr = r.MPI.ID + " " + r.MPI.Version
+		}
+		env := r.Env.Name
+		if env == "" {
+			env = "-"
+		}
+		nnodes := "-"
+		if r.NNodes > 0 {
+			nnodes = strconv.Itoa(r.NNodes)
+		}
+		state, exitCode, failure := "-", "-", "-"
+		if r.JobResult != nil {
+			if r.JobResult.State != "" {
+				state = r.JobResult.State
+			}
+			exitCode = strconv.Itoa(r.JobResult.ExitCode)
+			if r.JobResult.Failure != job.FailureNone {
+				failure = r.JobResult.Failure
+			}
+		}
+		result := "FAIL"
+		if r.Err == nil && r.Result.Pass {
+			result = "PASS"
+		}
+		if r.Err != nil && r.JobResult == nil {
+			failure = r.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", mpiStr, r.NP, nnodes, env, state, exitCode, result, failure)
+	}
+	w.Flush()
+	return buf.String()
+}
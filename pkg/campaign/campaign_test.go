@@ -0,0 +1,97 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package campaign
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gvallee/go_exec/pkg/results"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/jm"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
+)
+
+func TestCombinations(t *testing.T) {
+	m := Matrix{
+		MPIDirs:     []string{"/opt/openmpi", "/opt/mpich"},
+		NP:          []int{2, 4},
+		EnvVariants: []EnvVariant{{Name: "ucx"}, {Name: "ofi"}},
+	}
+	combinations := m.Combinations()
+	if len(combinations) != 8 {
+		t.Fatalf("Combinations() returned %d combinations instead of 8", len(combinations))
+	}
+	last := combinations[len(combinations)-1]
+	if last.MPIDir != "/opt/mpich" || last.NP != 4 || last.NNodes != 0 || last.Env.Name != "ofi" {
+		t.Fatalf("invalid last combination: %+v", last)
+	}
+
+	m.NNodes = []int{1, 2}
+	if len(m.Combinations()) != 16 {
+		t.Fatalf("Combinations() returned %d combinations instead of 16", len(m.Combinations()))
+	}
+}
+
+func TestExecuteInvalidMPI(t *testing.T) {
+	cfg := Config{
+		Name:   "test",
+		Matrix: Matrix{MPIDirs: []string{t.TempDir()}, NP: []int{1, 2}},
+	}
+	var jobmgr jm.JM
+	var sysCfg sys.Config
+	runs, err := Execute(&cfg, &jobmgr, &sysCfg)
+	if err != nil {
+		t.Fatalf("Execute() failed: %s", err)
+	}
+	if len(runs) != 2 || runs[0].Err == nil || runs[1].Err == nil {
+		t.Fatalf("Execute() did not report the invalid MPI directory: %+v", runs)
+	}
+
+	cfg.Matrix.NP = nil
+	_, err = Execute(&cfg, &jobmgr, &sysCfg)
+	if err == nil {
+		t.Fatalf("Execute() succeeded without any number of ranks")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	runs := []Run{
+		{
+			Combination: Combination{MPIDir: "/opt/openmpi", NP: 2, NNodes: 1, Env: EnvVariant{Name: "ucx"}},
+			MPI:         implem.Info{ID: implem.OMPI, Version: "4.1.5"},
+			JobResult:   &job.Result{State: job.StateCompleted},
+			Result:      results.Result{Pass: true},
+		},
+		{
+			Combination: Combination{MPIDir: "/opt/openmpi", NP: 4},
+			MPI:         implem.Info{ID: implem.OMPI, Version: "4.1.5"},
+			JobResult:   &job.Result{State: job.StateFailed, ExitCode: 1, Failure: job.FailureApplication},
+		},
+		{
+			Combination: Combination{MPIDir: "/opt/unknown", NP: 2},
+			Err:         fmt.Errorf("unable to detect MPI"),
+		},
+	}
+	lines := strings.Split(strings.TrimSpace(Summary(runs)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Summary() returned %d lines instead of 4:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	expected := [][]string{
+		{"MPI", "NP", "NODES", "ENV", "STATE", "EXIT", "RESULT", "FAILURE"},
+		{implem.OMPI, "4.1.5", "2", "1", "ucx", job.StateCompleted, "0", "PASS", "-"},
+		{implem.OMPI, "4.1.5", "4", "-", "-", job.StateFailed, "1", "FAIL", job.FailureApplication},
+		{"/opt/unknown", "2", "-", "-", "-", "-", "FAIL", "unable", "to", "detect", "MPI"},
+	}
+	for idx, line := range lines {
+		fields := strings.Fields(line)
+		if strings.Join(fields, " ") != strings.Join(expected[idx], " ") {
+			t.Fatalf("line %d of the summary is %q instead of %q", idx, line, strings.Join(expected[idx], " "))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package container describes how the application of a job is executed within a container image,
+// using the MPI implementation of the host to start the ranks (hybrid model).
+package container
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
+)
+
+const (
+	// RuntimeApptainer is the Apptainer container runtime (default)
+	RuntimeApptainer = "apptainer"
+
+	// RuntimeSingularity is the Singularity container runtime
+	RuntimeSingularity = "singularity"
+)
+
+// Spec specifies the container in which the application of a job is executed
+type Spec struct {
+	// Image is the path to the image of the container
+	Image string
+
+	// Runtime is the container runtime to use (RuntimeApptainer by default)
+	Runtime string
+
+	// Binds is the list of bind mounts, using the format of the runtime (e.g., /scratch or /data:/mnt:ro)
+	Binds []string
+
+	// Env is a set of environment variables to set in the container
+	Env map[string]string
+
+	// Options is a list of extra options passed as they are to the exec command of the runtime (e.g., --nv)
+	Options []string
+
+	// MPIID is the identifier of the MPI implementation in the image (e.g., openmpi), if any.
+	// The host MPI implementation must be the same.
+	MPIID string
+
+	// MPIVersion is the version constraint the host MPI implementation must satisfy to be compatible
+	// with the MPI implementation in the image (e.g., ">=4.1,<4.2"), if any
+	MPIVersion string
+}
+
+// GetRuntime returns the container runtime to use
+func (s *Spec) GetRuntime() string {
+	if s.Runtime == "" {
+		return RuntimeApptainer
+	}
+	return s.Runtime
+}
+
+// Validate checks that the container specification is consistent
+func (s *Spec) Validate() error {
+	if s.Image == "" {
+		return fmt.Errorf("undefined container image")
+	}
+	switch s.GetRuntime() {
+	case RuntimeApptainer, RuntimeSingularity:
+	default:
+		return fmt.Errorf("unsupported container runtime: %s", s.Runtime)
+	}
+	return nil
+}
+
+// CheckHostMPI checks that the MPI implementation of the host is compatible with the one declared for the image
+func (s *Spec) CheckHostMPI(hostMPI *implem.Info) error {
+	if s.MPIID == "" && s.MPIVersion == "" {
+		return nil
+	}
+	if hostMPI == nil || hostMPI.ID == "" {
+		return fmt.Errorf("the image of %s requires MPI but the host MPI implementation is unknown", s.Image)
+	}
+	if s.MPIID != "" && s.MPIID != hostMPI.ID {
+		return fmt.Errorf("the image of %s uses %s but the host MPI implementation is %s", s.Image, s.MPIID, hostMPI.ID)
+	}
+	if s.MPIVersion != "" {
+		ok, err := hostMPI.Satisfies(s.MPIVersion)
+		if err != nil {
+			return fmt.Errorf("unable to check the version of the host MPI implementation: %w", err)
+		}
+		if !ok {
+			return fmt.Errorf("host %s %s does not satisfy %s, required by %s", hostMPI.ID, hostMPI.Version, s.MPIVersion, s.Image)
+		}
+	}
+	return nil
+}
+
+// GetExecArgs returns the command executing a binary with its arguments in the container,
+// e.g., apptainer exec --nv image app arg1
+func (s *Spec) GetExecArgs(binPath string, binArgs []string) ([]string, error) {
+	err := s.Validate()
+	if err != nil {
+		return nil, err
+	}
+	args := []string{s.GetRuntime(), "exec"}
+	for _, b := range s.Binds {
+		args = append(args, "--bind", b)
+	}
+	var names []string
+	for name := range s.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "--env", name+"="+s.Env[name])
+	}
+	args = append(args, s.Options...)
+	args = append(args, s.Image, binPath)
+	return append(args, binArgs...), nil
+}
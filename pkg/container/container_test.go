@@ -0,0 +1,61 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package container
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
+)
+
+func TestGetExecArgs(t *testing.T) {
+	s := &Spec{
+		Image:   "/images/app.sif",
+		Binds:   []string{"/scratch", "/data:/mnt:ro"},
+		Env:     map[string]string{"OMP_NUM_THREADS": "4", "A": "1"},
+		Options: []string{"--nv"},
+	}
+	args, err := s.GetExecArgs("/opt/app/bin/app", []string{"-n", "10"})
+	if err != nil {
+		t.Fatalf("GetExecArgs() failed: %s", err)
+	}
+	expected := "apptainer exec --bind /scratch --bind /data:/mnt:ro --env A=1 --env OMP_NUM_THREADS=4 --nv /images/app.sif /opt/app/bin/app -n 10"
+	if strings.Join(args, " ") != expected {
+		t.Fatalf("GetExecArgs() returned %q instead of %q", strings.Join(args, " "), expected)
+	}
+
+	s = &Spec{Runtime: "docker", Image: "app"}
+	_, err = s.GetExecArgs("app", nil)
+	if err == nil {
+		t.Fatalf("GetExecArgs() succeeded with an unsupported runtime")
+	}
+}
+
+func TestCheckHostMPI(t *testing.T) {
+	s := &Spec{Image: "app.sif", MPIID: implem.OMPI, MPIVersion: ">=4.1,<4.2"}
+	tests := []struct {
+		host  *implem.Info
+		valid bool
+	}{
+		{host: &implem.Info{ID: implem.OMPI, Version: "4.1.5"}, valid: true},
+		{host: &implem.Info{ID: implem.OMPI, Version: "5.0.1"}, valid: false},
+		{host: &implem.Info{ID: implem.MPICH, Version: "4.1"}, valid: false},
+		{host: nil, valid: false},
+	}
+	for _, tt := range tests {
+		err := s.CheckHostMPI(tt.host)
+		if (err == nil) != tt.valid {
+			t.Fatalf("CheckHostMPI(%v) returned %v", tt.host, err)
+		}
+	}
+
+	s = &Spec{Image: "app.sif"}
+	err := s.CheckHostMPI(nil)
+	if err != nil {
+		t.Fatalf("CheckHostMPI() failed without MPI requirement: %s", err)
+	}
+}
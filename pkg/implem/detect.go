@@ -0,0 +1,209 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package implem
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gvallee/go_exec/pkg/advexec"
+	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/mpich"
+	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/mvapich2"
+	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/openmpi"
+	"github.com/gvallee/go_util/pkg/util"
+)
+
+// ParseVersionFn is a "function pointer" that extracts the version of an MPI implementation
+// from the output of its version command
+type ParseVersionFn func(output string) (string, error)
+
+// RetryEnvFn is a "function pointer" that returns extra environment variables to use when
+// the version command of an MPI implementation fails with the default environment
+type RetryEnvFn func(dir string) []string
+
+// IntrospectFn is a "function pointer" that gathers the capabilities of an MPI implementation once
+// detected. versionOutput is the output of the version command.
+type IntrospectFn func(d *Detector, i *Info, versionOutput string, env []string) error
+
+// Detector gathers everything required to detect a given MPI implementation from its
+// installation directory
+type Detector struct {
+	// ID is the identifier of the MPI implementation the detector is looking for
+	ID string
+
+	// VersionBin is the name of the binary, in the bin directory of the installation, used
+	// to get the version of the implementation. The binary must exist for the detector to apply.
+	VersionBin string
+
+	// VersionArgs is the list of arguments to pass to VersionBin to get the version
+	VersionArgs []string
+
+	// ParseVersion extracts the version from the output of the version command
+	ParseVersion ParseVersionFn
+
+	// RetryEnv is optional; when set and the version command fails, the command is executed
+	// a second time with the returned environment variables
+	RetryEnv RetryEnvFn
+
+	// Introspect is optional; when set, it is called to gather the capabilities of the implementation
+	// after a successful detection. Failing to introspect the implementation is not fatal.
+	Introspect IntrospectFn
+}
+
+// detectors is the ordered list of registered detectors. Order matters since some
+// implementations share binaries, e.g., MVAPICH2 must be checked before MPICH.
+var detectors []Detector
+
+func init() {
+	RegisterDetector(Detector{
+		ID:           openmpi.ID,
+		VersionBin:   openmpi.VersionBin,
+		VersionArgs:  []string{openmpi.VersionArg},
+		ParseVersion: openmpi.ParseVersion,
+		RetryEnv:     openmpi.RetryEnv,
+		Introspect:   introspectOpenMPI,
+	})
+	// Always check for MVAPICH2 before MPICH since they share some code, otherwise MVAPICH2 is not correctly detected
+	RegisterDetector(Detector{
+		ID:           mvapich2.ID,
+		VersionBin:   mvapich2.VersionBin,
+		VersionArgs:  []string{mvapich2.VersionArg},
+		ParseVersion: mvapich2.ParseVersion,
+	})
+	RegisterDetector(Detector{
+		ID:           mpich.ID,
+		VersionBin:   mpich.VersionBin,
+		VersionArgs:  []string{mpich.VersionArg},
+		ParseVersion: mpich.ParseVersion,
+		Introspect:   introspectMPICH,
+	})
+}
+
+// RegisterDetector adds a detector at the end of the list of detectors. Detectors are
+// tried in the order they have been registered.
+func RegisterDetector(d Detector) {
+	detectors = append(detectors, d)
+}
+
+// Detectors returns the ordered list of registered detectors
+func Detectors() []Detector {
+	return append([]Detector(nil), detectors...)
+}
+
+// DefaultEnv returns the environment to use to run a command from an MPI installation
+// when the caller did not specify any
+func DefaultEnv(dir string) []string {
+	newLDPath := filepath.Join(dir, "lib") + ":" + os.Getenv("LD_LIBRARY_PATH")
+	newPath := filepath.Join(dir, "bin") + ":" + os.Getenv("PATH")
+	return []string{"LD_LIBRARY_PATH=" + newLDPath, "PATH=" + newPath}
+}
+
+// Run executes a binary from the bin directory of an installation of the implementation, using the
+// default environment if env is nil and retrying with the detector's retry environment on failure
+func (d *Detector) Run(dir string, env []string, bin string, args []string) advexec.Result {
+	if env == nil {
+		env = DefaultEnv(dir)
+	}
+
+	var cmd advexec.Advcmd
+	cmd.BinPath = filepath.Join(dir, "bin", bin)
+	cmd.CmdArgs = args
+	cmd.ExecDir = filepath.Join(dir, "bin")
+	cmd.Env = env
+	res := cmd.Run()
+	if res.Err != nil && d.RetryEnv != nil {
+		// We create a new command to avoid "exec: already started" issues.
+		var retryCmd advexec.Advcmd
+		retryCmd.BinPath = cmd.BinPath
+		retryCmd.CmdArgs = cmd.CmdArgs
+		retryCmd.ExecDir = cmd.ExecDir
+		retryCmd.Env = append(append([]string{}, env...), d.RetryEnv(dir)...)
+		res = retryCmd.Run()
+	}
+	return res
+}
+
+// detect tries to figure out the version of the implementation handled by the detector
+// that is installed in a given directory and returns the output of the version command
+func (d *Detector) detect(dir string, env []string) (string, string, error) {
+	targetBin := filepath.Join(dir, "bin", d.VersionBin)
+	if !util.FileExists(targetBin) {
+		return "", "", fmt.Errorf("%s does not exist, not a %s implementation", targetBin, d.ID)
+	}
+
+	res := d.Run(dir, env, d.VersionBin, d.VersionArgs)
+	if res.Err != nil {
+		log.Printf("unable to run %s: %s; stdout: %s; stderr: %s", targetBin, res.Err, res.Stdout, res.Stderr)
+		return "", "", fmt.Errorf("unable to execute %s: %w", targetBin, res.Err)
+	}
+
+	version, err := d.ParseVersion(res.Stdout)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to parse the %s version: %w", d.ID, err)
+	}
+	return version, res.Stdout, nil
+}
+
+// Detect tries to figure out the version of the implementation handled by the detector
+// that is installed in a given directory
+func (d *Detector) Detect(dir string, env []string) (string, error) {
+	version, _, err := d.detect(dir, env)
+	return version, err
+}
+
+// DetectFromDir goes through all the registered detectors, in order, and returns the
+// details of the first MPI implementation that is found in a given directory
+func DetectFromDir(dir string, env []string) (Info, error) {
+	var i Info
+	for idx := range detectors {
+		d := &detectors[idx]
+		version, output, err := d.detect(dir, env)
+		if err != nil {
+			continue
+		}
+		i.ID = d.ID
+		i.Version = version
+		i.InstallDir = dir
+		if d.Introspect != nil {
+			err = d.Introspect(d, &i, output, env)
+			if err != nil {
+				log.Printf("unable to introspect %s in %s: %s", d.ID, dir, err)
+			}
+		}
+		return i, nil
+	}
+	return i, fmt.Errorf("unable to detect any supported MPI implementation from %s", dir)
+}
+
+func introspectOpenMPI(d *Detector, i *Info, versionOutput string, env []string) error {
+	res := d.Run(i.InstallDir, env, openmpi.VersionBin, openmpi.CapabilitiesArgs)
+	if res.Err != nil {
+		return fmt.Errorf("unable to get the capabilities of Open MPI: %w", res.Err)
+	}
+	caps, err := openmpi.ParseCapabilities(res.Stdout)
+	if err != nil {
+		return err
+	}
+	i.OMPICapabilities = caps
+	return nil
+}
+
+func introspectMPICH(d *Detector, i *Info, versionOutput string, env []string) error {
+	caps, err := mpich.ParseCapabilities(versionOutput)
+	if err != nil {
+		return err
+	}
+	if util.FileExists(filepath.Join(i.InstallDir, "bin", mpich.MPICHVersionBin)) {
+		res := d.Run(i.InstallDir, env, mpich.MPICHVersionBin, nil)
+		if res.Err == nil {
+			caps.ParseMPICHVersionOutput(res.Stdout)
+		}
+	}
+	i.MPICHCapabilities = caps
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package implem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func createFakeBin(t *testing.T, dir string, name string, output string) {
+	binDir := filepath.Join(dir, "bin")
+	err := os.MkdirAll(binDir, 0755)
+	if err != nil {
+		t.Fatalf("unable to create %s: %s", binDir, err)
+	}
+	script := "#!/bin/sh\ncat <<'EOF'\n" + output + "\nEOF\n"
+	err = os.WriteFile(filepath.Join(binDir, name), []byte(script), 0755)
+	if err != nil {
+		t.Fatalf("unable to create fake %s: %s", name, err)
+	}
+}
+
+func TestLoadMVAPICH2(t *testing.T) {
+	dir := t.TempDir()
+	// MVAPICH2 also provides a MPICH-like mpirun, the detection must not mislabel it as MPICH
+	createFakeBin(t, dir, "mpirun", "HYDRA build details:\n    Version:                                 3.2.1")
+	createFakeBin(t, dir, "mpirun_rsh", "MVAPICH2 Version:       2.3.7")
+
+	var i Info
+	i.InstallDir = dir
+	err := i.Load(nil)
+	if err != nil {
+		t.Fatalf("Load() failed: %s", err)
+	}
+	if i.ID != MVAPICH2 || i.Version != "2.3.7" {
+		t.Fatalf("Load() detected %s %s instead of %s 2.3.7", i.ID, i.Version, MVAPICH2)
+	}
+}
+
+func TestDetectFromDir(t *testing.T) {
+	tests := []struct {
+		name            string
+		bin             string
+		output          string
+		expectedID      string
+		expectedVersion string
+	}{
+		{
+			name:            "openmpi",
+			bin:             "ompi_info",
+			output:          "Open MPI v4.1.5\n\nhttp://www.open-mpi.org/community/help/",
+			expectedID:      OMPI,
+			expectedVersion: "4.1.5",
+		},
+		{
+			name:            "mpich",
+			bin:             "mpirun",
+			output:          "HYDRA build details:\n    Version:                                 4.0b1",
+			expectedID:      MPICH,
+			expectedVersion: "4.0b1",
+		},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		createFakeBin(t, dir, tt.bin, tt.output)
+		i, err := DetectFromDir(dir, nil)
+		if err != nil {
+			t.Fatalf("%s: DetectFromDir() failed: %s", tt.name, err)
+		}
+		if i.ID != tt.expectedID || i.Version != tt.expectedVersion || i.InstallDir != dir {
+			t.Fatalf("%s: DetectFromDir() returned %+v", tt.name, i)
+		}
+	}
+
+	_, err := DetectFromDir(t.TempDir(), nil)
+	if err == nil {
+		t.Fatalf("DetectFromDir() succeeded on an empty directory")
+	}
+}
@@ -22,6 +22,18 @@ const (
 
 	// MVAPICH2 us the identifier for MVAPICH2
 	MVAPICH2 = mvapich2.ID
+
+	// SourceDir is the source of an MPI implementation found by looking at a directory
+	SourceDir = "dir"
+
+	// SourcePath is the source of an MPI implementation found through PATH
+	SourcePath = "path"
+
+	// SourceModule is the source of an MPI implementation found through Lmod or Environment Modules
+	SourceModule = "module"
+
+	// SourceSpack is the source of an MPI implementation installed with Spack
+	SourceSpack = "spack"
 )
 
 // Info gathers all data about a specific MPI implementation
@@ -34,12 +46,30 @@ type Info struct {
 
 	// InstallDir is where the MPI implementation is installed
 	InstallDir string
+
+	// Source is how the MPI implementation was found (optional), e.g., SourcePath or SourceSpack
+	Source string
+
+	// SourceRef is the reference to the MPI implementation within its source (optional),
+	// e.g., the name of the module or the Spack spec
+	SourceRef string
+
+	// OMPICapabilities describes what the Open MPI build supports (only set for Open MPI)
+	OMPICapabilities *openmpi.Capabilities
+
+	// MPICHCapabilities describes what the MPICH build supports (only set for MPICH)
+	MPICHCapabilities *mpich.Capabilities
 }
 
 // IsMPI checks if information passed in is an MPI implementation
 func IsMPI(i *Info) bool {
-	if i != nil && (i.ID == OMPI || i.ID == MPICH || i.ID == MVAPICH2) {
-		return true
+	if i == nil {
+		return false
+	}
+	for _, d := range detectors {
+		if i.ID == d.ID {
+			return true
+		}
 	}
 
 	return false
@@ -53,16 +83,13 @@ func IsMPI(i *Info) bool {
 // If no suitable implementation can be found, the function returns an error
 func (i *Info) Load(env []string) error {
 	if i.InstallDir != "" && (i.ID == "" || i.Version == "") {
-		var err error
-		i.ID, i.Version, err = openmpi.DetectFromDir(i.InstallDir, env)
-		if err == nil {
-			return nil
-		}
-		i.ID, i.Version, err = mpich.DetectFromDir(i.InstallDir, env)
-		if err == nil {
-			return nil
+		detected, err := DetectFromDir(i.InstallDir, env)
+		if err != nil {
+			return fmt.Errorf("unable to detect MPI implementation from %s: %w", i.InstallDir, err)
 		}
-		return fmt.Errorf("unable to detect MPI implementation from %s", i.InstallDir)
+		detected.Source = i.Source
+		detected.SourceRef = i.SourceRef
+		*i = detected
 	}
 	return nil
 }
@@ -0,0 +1,305 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package implem
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pre-release kinds, ordered so that a release is always greater than any of its pre-releases
+const (
+	preAlpha = iota
+	preBeta
+	preRC
+	preNone
+)
+
+// Version is the parsed version of an MPI implementation. It handles the formats used by all
+// the supported implementations, e.g., "4.1.5" and "5.0.0rc12" (Open MPI), "4.0b1" (MPICH),
+// "2.3b" and "2.3.7-1" (MVAPICH2).
+type Version struct {
+	// Numbers are the numeric components of the version, e.g., [4 1 5] for 4.1.5
+	Numbers []int
+
+	// preKind is the kind of pre-release (alpha, beta, rc) or preNone for a release
+	preKind int
+
+	// PreRelease is the pre-release tag as it appears in the version string (e.g., "b1", "rc12"), empty for a release
+	PreRelease string
+
+	// preNum is the number of the pre-release, e.g., 12 for rc12
+	preNum int
+
+	// Post is the package release number that follows a version, e.g., 1 for MVAPICH2 2.3.7-1
+	Post int
+
+	// Metadata is any trailing information that is not used for comparison (e.g., a vendor suffix)
+	Metadata string
+
+	// Raw is the version string that was parsed
+	Raw string
+}
+
+// ParseVersion parses the version string of an MPI implementation
+func ParseVersion(str string) (*Version, error) {
+	v := new(Version)
+	v.Raw = str
+	s := strings.TrimSpace(str)
+	s = strings.TrimPrefix(s, "v")
+	if s == "" {
+		return nil, fmt.Errorf("empty version")
+	}
+
+	// Split off the post-release number or metadata
+	if idx := strings.IndexAny(s, "-+"); idx != -1 {
+		suffix := s[idx+1:]
+		post, err := strconv.Atoi(suffix)
+		if s[idx] == '-' && err == nil {
+			v.Post = post
+		} else {
+			v.Metadata = suffix
+		}
+		s = s[:idx]
+	}
+
+	// The numeric part ends at the first character that is neither a digit nor a dot
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	numPart := s
+	pre := ""
+	if end != -1 {
+		numPart = s[:end]
+		pre = s[end:]
+	}
+	numPart = strings.TrimSuffix(numPart, ".")
+	if numPart == "" {
+		return nil, fmt.Errorf("invalid version %q: no numeric component", str)
+	}
+	for _, t := range strings.Split(numPart, ".") {
+		n, err := strconv.Atoi(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q: %w", str, err)
+		}
+		v.Numbers = append(v.Numbers, n)
+	}
+
+	v.preKind = preNone
+	if pre != "" {
+		var kind string
+		switch {
+		case strings.HasPrefix(pre, "alpha"):
+			v.preKind, kind = preAlpha, "alpha"
+		case strings.HasPrefix(pre, "beta"):
+			v.preKind, kind = preBeta, "beta"
+		case strings.HasPrefix(pre, "rc"):
+			v.preKind, kind = preRC, "rc"
+		case strings.HasPrefix(pre, "a"):
+			v.preKind, kind = preAlpha, "a"
+		case strings.HasPrefix(pre, "b"):
+			v.preKind, kind = preBeta, "b"
+		default:
+			return nil, fmt.Errorf("invalid version %q: unknown pre-release tag %q", str, pre)
+		}
+		v.PreRelease = pre
+		num := strings.TrimPrefix(pre, kind)
+		if num != "" {
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version %q: invalid pre-release number %q", str, num)
+			}
+			v.preNum = n
+		}
+	}
+
+	return v, nil
+}
+
+// IsPreRelease checks whether the version is a pre-release (alpha, beta or release candidate)
+func (v *Version) IsPreRelease() bool {
+	return v.preKind != preNone
+}
+
+// String returns the version as a string
+func (v *Version) String() string {
+	return v.Raw
+}
+
+func compareInts(a int, b int) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// Compare compares two versions and returns -1, 0 or 1 if v is respectively older, identical
+// or newer than o. Missing numeric components are treated as 0, i.e., 4.1 and 4.1.0 are
+// identical, and a pre-release is always older than the corresponding release.
+func (v *Version) Compare(o *Version) int {
+	n := len(v.Numbers)
+	if len(o.Numbers) > n {
+		n = len(o.Numbers)
+	}
+	for idx := 0; idx < n; idx++ {
+		a, b := 0, 0
+		if idx < len(v.Numbers) {
+			a = v.Numbers[idx]
+		}
+		if idx < len(o.Numbers) {
+			b = o.Numbers[idx]
+		}
+		if c := compareInts(a, b); c != 0 {
+			return c
+		}
+	}
+	if c := compareInts(v.preKind, o.preKind); c != 0 {
+		return c
+	}
+	if c := compareInts(v.preNum, o.preNum); c != 0 {
+		return c
+	}
+	return compareInts(v.Post, o.Post)
+}
+
+type constraintTerm struct {
+	op      string
+	version *Version
+}
+
+// Constraint is a set of version requirements that must all be satisfied, e.g., ">=4.1,<5"
+type Constraint struct {
+	terms []constraintTerm
+}
+
+// constraintOps is the list of supported operators; two-characters operators must come first
+var constraintOps = []string{">=", "<=", "==", "!=", ">", "<", "="}
+
+// ParseConstraint parses a comma-separated list of version requirements such as ">=4.1,<5".
+// A requirement without operator is an equality requirement.
+func ParseConstraint(str string) (*Constraint, error) {
+	c := new(Constraint)
+	for _, t := range strings.Split(str, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		op := "=="
+		for _, candidate := range constraintOps {
+			if strings.HasPrefix(t, candidate) {
+				op = candidate
+				t = strings.TrimSpace(strings.TrimPrefix(t, candidate))
+				break
+			}
+		}
+		if op == "=" {
+			op = "=="
+		}
+		v, err := ParseVersion(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint %q: %w", str, err)
+		}
+		c.terms = append(c.terms, constraintTerm{op: op, version: v})
+	}
+	if len(c.terms) == 0 {
+		return nil, fmt.Errorf("empty constraint")
+	}
+	return c, nil
+}
+
+// Check returns true if the version satisfies all the requirements of the constraint
+func (c *Constraint) Check(v *Version) bool {
+	for _, t := range c.terms {
+		r := v.Compare(t.version)
+		ok := false
+		switch t.op {
+		case "==":
+			ok = r == 0
+		case "!=":
+			ok = r != 0
+		case ">":
+			ok = r > 0
+		case ">=":
+			ok = r >= 0
+		case "<":
+			ok = r < 0
+		case "<=":
+			ok = r <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Satisfies checks whether the version satisfies a constraint such as ">=4.1,<5"
+func (v *Version) Satisfies(constraint string) (bool, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	return c.Check(v), nil
+}
+
+// ParsedVersion returns the parsed version of the MPI implementation
+func (i *Info) ParsedVersion() (*Version, error) {
+	if i.Version == "" {
+		return nil, fmt.Errorf("undefined version for %s", i.ID)
+	}
+	return ParseVersion(i.Version)
+}
+
+// Satisfies checks whether the version of the MPI implementation satisfies a constraint such as ">=4.1,<5"
+func (i *Info) Satisfies(constraint string) (bool, error) {
+	v, err := i.ParsedVersion()
+	if err != nil {
+		return false, err
+	}
+	return v.Satisfies(constraint)
+}
+
+// Select returns the most recent MPI implementation from a list of candidates that matches
+// a given implementation ID and satisfies a version constraint. An empty ID or an empty
+// constraint matches any implementation or any version.
+func Select(candidates []Info, id string, constraint string) (*Info, error) {
+	var c *Constraint
+	if constraint != "" {
+		var err error
+		c, err = ParseConstraint(constraint)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var selected *Info
+	var selectedVersion *Version
+	for idx := range candidates {
+		candidate := &candidates[idx]
+		if id != "" && candidate.ID != id {
+			continue
+		}
+		v, err := candidate.ParsedVersion()
+		if err != nil {
+			continue
+		}
+		if c != nil && !c.Check(v) {
+			continue
+		}
+		if selected == nil || v.Compare(selectedVersion) > 0 {
+			selected = candidate
+			selectedVersion = v
+		}
+	}
+	if selected == nil {
+		return nil, fmt.Errorf("no MPI implementation matching %q %q", id, constraint)
+	}
+	return selected, nil
+}
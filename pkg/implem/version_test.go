@@ -0,0 +1,105 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package implem
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{a: "4.1.5", b: "4.1.5", expected: 0},
+		{a: "4.1", b: "4.1.0", expected: 0},
+		{a: "v4.1.5", b: "4.1.4", expected: 1},
+		{a: "4.1.5", b: "4.10.0", expected: -1},
+		{a: "5.0.0rc12", b: "5.0.0", expected: -1},
+		{a: "5.0.0rc12", b: "5.0.0rc2", expected: 1},
+		{a: "4.0b1", b: "4.0a2", expected: 1},
+		{a: "4.0b1", b: "4.0rc1", expected: -1},
+		{a: "4.0b1", b: "3.4.2", expected: 1},
+		{a: "2.3b", b: "2.3", expected: -1},
+		{a: "2.3.7-1", b: "2.3.7", expected: 1},
+		{a: "2.3.7-1", b: "2.3.8", expected: -1},
+		{a: "4.1.5+vendor", b: "4.1.5", expected: 0},
+	}
+
+	for _, tt := range tests {
+		a, err := ParseVersion(tt.a)
+		if err != nil {
+			t.Fatalf("ParseVersion(%s) failed: %s", tt.a, err)
+		}
+		b, err := ParseVersion(tt.b)
+		if err != nil {
+			t.Fatalf("ParseVersion(%s) failed: %s", tt.b, err)
+		}
+		if r := a.Compare(b); r != tt.expected {
+			t.Fatalf("comparing %s and %s returned %d instead of %d", tt.a, tt.b, r, tt.expected)
+		}
+	}
+
+	for _, invalid := range []string{"", "rc1", "4.1.x", "4.1dev"} {
+		_, err := ParseVersion(invalid)
+		if err == nil {
+			t.Fatalf("ParseVersion(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		expected   bool
+	}{
+		{version: "4.1.5", constraint: ">=4.1,<5", expected: true},
+		{version: "4.0.7", constraint: ">=4.1,<5", expected: false},
+		{version: "5.0.3", constraint: ">=4.1,<5", expected: false},
+		{version: "5.0.0rc12", constraint: ">=5", expected: false},
+		{version: "4.0b1", constraint: "4.0b1", expected: true},
+		{version: "2.3.7-1", constraint: "> 2.3.7", expected: true},
+		{version: "3.4.2", constraint: "!=3.4.2", expected: false},
+	}
+
+	for _, tt := range tests {
+		i := Info{ID: MPICH, Version: tt.version}
+		ok, err := i.Satisfies(tt.constraint)
+		if err != nil {
+			t.Fatalf("Satisfies(%s) failed: %s", tt.constraint, err)
+		}
+		if ok != tt.expected {
+			t.Fatalf("%s satisfies %s: %v instead of %v", tt.version, tt.constraint, ok, tt.expected)
+		}
+	}
+
+	_, err := ParseConstraint(">=abc")
+	if err == nil {
+		t.Fatalf("ParseConstraint() succeeded with an invalid constraint")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	candidates := []Info{
+		{ID: OMPI, Version: "4.0.7", InstallDir: "/opt/ompi-4.0.7"},
+		{ID: OMPI, Version: "4.1.5", InstallDir: "/opt/ompi-4.1.5"},
+		{ID: OMPI, Version: "5.0.3", InstallDir: "/opt/ompi-5.0.3"},
+		{ID: MPICH, Version: "4.1.2", InstallDir: "/opt/mpich-4.1.2"},
+	}
+
+	selected, err := Select(candidates, OMPI, ">=4.1,<5")
+	if err != nil {
+		t.Fatalf("Select() failed: %s", err)
+	}
+	if selected.InstallDir != "/opt/ompi-4.1.5" {
+		t.Fatalf("Select() returned %s", selected.InstallDir)
+	}
+
+	_, err = Select(candidates, MVAPICH2, "")
+	if err == nil {
+		t.Fatalf("Select() succeeded without any suitable candidate")
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package jm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gvallee/go_exec/pkg/advexec"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/nodelist"
+)
+
+const (
+	// sacctFormat is the list of fields requested to sacct, in the order they are parsed
+	sacctFormat = "JobID,State,ExitCode,Start,End,ElapsedRaw,NodeList,MaxRSS,TotalCPU"
+
+	// sacctTimeFormat is the format of the dates reported by sacct
+	sacctTimeFormat = "2006-01-02T15:04:05"
+)
+
+// parseSacctExitCode parses an exit code reported by sacct, e.g., "1:0", which includes the signal
+func parseSacctExitCode(str string) (int, int, error) {
+	tokens := strings.SplitN(str, ":", 2)
+	code, err := strconv.Atoi(tokens[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid exit code %s: %w", str, err)
+	}
+	if len(tokens) == 1 {
+		return code, 0, nil
+	}
+	signal, err := strconv.Atoi(tokens[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid exit code %s: %w", str, err)
+	}
+	return code, signal, nil
+}
+
+// parseSacctTime parses a date reported by sacct; "Unknown" or "None" give a zero time
+func parseSacctTime(str string) (time.Time, error) {
+	switch str {
+	case "", "Unknown", "None":
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(sacctTimeFormat, str, time.Local)
+}
+
+// parseSacctMemory parses a memory size reported by sacct, e.g., "1234K", and returns it in bytes
+func parseSacctMemory(str string) (int64, error) {
+	if str == "" {
+		return 0, nil
+	}
+	multiplier := int64(1)
+	switch str[len(str)-1] {
+	case 'K':
+		multiplier = 1024
+	case 'M':
+		multiplier = 1024 * 1024
+	case 'G':
+		multiplier = 1024 * 1024 * 1024
+	case 'T':
+		multiplier = 1024 * 1024 * 1024 * 1024
+	}
+	if multiplier != 1 {
+		str = str[:len(str)-1]
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory size %s: %w", str, err)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// parseSacctDuration parses a duration reported by sacct, e.g., "1-02:03:04", "02:03:04" or "03:04.567"
+func parseSacctDuration(str string) (time.Duration, error) {
+	if str == "" {
+		return 0, nil
+	}
+	var d time.Duration
+	if idx := strings.Index(str, "-"); idx != -1 {
+		days, err := strconv.Atoi(str[:idx])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s: %w", str, err)
+		}
+		d += time.Duration(days) * 24 * time.Hour
+		str = str[idx+1:]
+	}
+	tokens := strings.Split(str, ":")
+	if len(tokens) > 3 {
+		return 0, fmt.Errorf("invalid duration %s", str)
+	}
+	units := []time.Duration{time.Second, time.Minute, time.Hour}
+	for idx := range tokens {
+		value, err := strconv.ParseFloat(tokens[len(tokens)-1-idx], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s: %w", str, err)
+		}
+		d += time.Duration(value * float64(units[idx]))
+	}
+	return d, nil
+}
+
+// parseSacctOutput parses the output of 'sacct --parsable2 --noheader' with sacctFormat. The first line
+// is the job itself, the following ones are its steps, which are used to get the maximum resident set size.
+func parseSacctOutput(output string) (*job.Accounting, error) {
+	var a *job.Accounting
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) != len(strings.Split(sacctFormat, ",")) {
+			return nil, fmt.Errorf("invalid sacct output: %s", line)
+		}
+		maxRSS, err := parseSacctMemory(fields[7])
+		if err != nil {
+			return nil, err
+		}
+		if a != nil {
+			// This is a step of the job
+			if maxRSS > a.MaxRSS {
+				a.MaxRSS = maxRSS
+			}
+			continue
+		}
+
+		a = new(job.Accounting)
+		a.MaxRSS = maxRSS
+		a.JobID, err = strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid job ID %s: %w", fields[0], err)
+		}
+		// The state may have details, e.g., "CANCELLED by 1000"
+		a.State = strings.Fields(fields[1] + " ")[0]
+		a.ExitCode, a.Signal, err = parseSacctExitCode(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		a.Start, err = parseSacctTime(fields[3])
+		if err != nil {
+			return nil, err
+		}
+		a.End, err = parseSacctTime(fields[4])
+		if err != nil {
+			return nil, err
+		}
+		elapsed, err := strconv.Atoi(fields[5])
+		if err != nil {
+			return nil, fmt.Errorf("invalid elapsed time %s: %w", fields[5], err)
+		}
+		a.Elapsed = time.Duration(elapsed) * time.Second
+		if fields[6] != "None assigned" {
+			a.Nodes, err = nodelist.Expand(fields[6])
+			if err != nil {
+				return nil, err
+			}
+		}
+		a.CPUTime, err = parseSacctDuration(fields[8])
+		if err != nil {
+			return nil, err
+		}
+	}
+	if a == nil {
+		return nil, fmt.Errorf("no accounting data")
+	}
+	return a, nil
+}
+
+func slurmAccounting(jobmgr *JM, j *job.Job) (*job.Accounting, error) {
+	if j.ID == 0 {
+		return nil, fmt.Errorf("undefined job ID")
+	}
+
+	var cmd advexec.Advcmd
+	var err error
+	cmd.BinPath, err = exec.LookPath("sacct")
+	if err != nil {
+		return nil, fmt.Errorf("sacct not found: %w", err)
+	}
+	cmd.CmdArgs = []string{"-j", strconv.Itoa(j.ID), "--parsable2", "--noheader", "--format=" + sacctFormat}
+	res := cmd.Run()
+	if res.Err != nil {
+		return nil, fmt.Errorf("sacct failed: %w (stderr: %s)", res.Err, res.Stderr)
+	}
+	return parseSacctOutput(res.Stdout)
+}
+
+// localNodes returns the names of the nodes a job launched from the local node runs on
+func localNodes(j *job.Job) []string {
+	var nodes []string
+	for _, n := range j.Nodes {
+		nodes = append(nodes, n.Name)
+	}
+	if len(nodes) == 0 {
+		hostname, err := os.Hostname()
+		if err == nil {
+			nodes = append(nodes, hostname)
+		}
+	}
+	return nodes
+}
+
+// setupOutputStreams creates the command of a job launched from the local node so that its output is written
+// to the buffers of the job as well as to its sinks, if any, as it is produced. The returned function must be
+// called once the command completed.
+func setupOutputStreams(cmd *advexec.Advcmd, j *job.Job) context.CancelFunc {
+	timeout := cmd.Timeout
+	if timeout == 0 {
+		timeout = advexec.CmdTimeout * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	j.OutBuffer.Reset()
+	j.ErrBuffer.Reset()
+	cmd.Cmd = exec.CommandContext(ctx, cmd.BinPath, cmd.CmdArgs...)
+	cmd.Cmd.Env = append(cmd.Cmd.Env, cmd.Env...)
+	cmd.Cmd.Stdout = &j.OutBuffer
+	if j.Stdout != nil {
+		cmd.Cmd.Stdout = io.MultiWriter(&j.OutBuffer, j.Stdout)
+	}
+	cmd.Cmd.Stderr = &j.ErrBuffer
+	if j.Stderr != nil {
+		cmd.Cmd.Stderr = io.MultiWriter(&j.ErrBuffer, j.Stderr)
+	}
+	return cancel
+}
+
+// runAndAccount runs the command of a job launched from the local node, streams its output (see Job.Stdout and
+// Job.Stderr) and records its accounting data
+func runAndAccount(cmd *advexec.Advcmd, j *job.Job) advexec.Result {
+	cancel := setupOutputStreams(cmd, j)
+	defer cancel()
+	start := time.Now()
+	res := cmd.Run()
+	res.Stdout = j.OutBuffer.String()
+	res.Stderr = j.ErrBuffer.String()
+	for _, w := range []io.Writer{j.Stdout, j.Stderr} {
+		if lw, ok := w.(*job.LineWriter); ok {
+			lw.Flush()
+		}
+	}
+	if cmd.Cmd.ProcessState != nil {
+		j.SetAccounting(job.AccountingFromProcess(cmd.Cmd.ProcessState, start, time.Now(), localNodes(j)))
+	}
+	return res
+}
+
+// localAccounting returns the accounting data recorded when the job was run from the local node
+func localAccounting(jobmgr *JM, j *job.Job) (*job.Accounting, error) {
+	a := j.GetAccounting()
+	if a == nil {
+		return nil, fmt.Errorf("no accounting data, the job did not run")
+	}
+	return a, nil
+}
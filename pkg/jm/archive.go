@@ -0,0 +1,388 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package jm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
+)
+
+const (
+	// archivesDir is the directory of sys.Config.Persistent where the archives of jobs are created by default
+	archivesDir = "archives"
+
+	// archiveManifestName is the name of the manifest in an archive
+	archiveManifestName = "MANIFEST.json"
+
+	// archiveManifestSuffix is the suffix of the manifest written next to an archive
+	archiveManifestSuffix = ".manifest.json"
+
+	// EntryMetadata is the kind of the archive entry with the metadata of the job
+	EntryMetadata = "metadata"
+
+	// EntryBatchScript is the kind of the archive entry with the batch script of the job
+	EntryBatchScript = "batch-script"
+
+	// EntryStdout is the kind of the archive entry with the output (stdout) of the job
+	EntryStdout = "stdout"
+
+	// EntryStderr is the kind of the archive entry with stderr of the job
+	EntryStderr = "stderr"
+
+	// EntryArtifact is the kind of the archive entries with the artifacts of the job
+	EntryArtifact = "artifact"
+)
+
+// ArchiveEntry is a file of a job that was captured in its archive, or skipped
+type ArchiveEntry struct {
+	// Name is the path to the file in the archive, relative to the top directory of the archive
+	Name string
+
+	// Kind is the kind of file (e.g., EntryStdout, EntryArtifact)
+	Kind string
+
+	// Source is the path to the archived file, if the file was not generated by the job manager
+	Source string
+
+	// Size is the size of the file in bytes
+	Size int64
+
+	// Skipped is the reason why the file is not in the archive, if it was skipped
+	Skipped string
+
+	// Padded is the number of zero bytes added at the end of the file in the archive, when the file was
+	// truncated while being archived
+	Padded int64
+}
+
+// ArchiveManifest lists what the archive of a job captured
+type ArchiveManifest struct {
+	// Job is the name of the job
+	Job string
+
+	// JobID is the identifier of the job assigned by the job manager, 0 if none
+	JobID int
+
+	// Archive is the path to the archive
+	Archive string
+
+	// Format is the compression format of the archive (e.g., job.ArchiveGzip)
+	Format string
+
+	// Created is when the archive was created
+	Created time.Time
+
+	// Size is the size in bytes of the files in the archive, before compression
+	Size int64
+
+	// Entries is the list of files of the job, in the order they were added to the archive
+	Entries []ArchiveEntry
+}
+
+// archiveItem is a file to add to an archive, read from Source or generated in content
+type archiveItem struct {
+	entry   ArchiveEntry
+	content []byte
+}
+
+// zeroReader is a reader returning zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// GetArchiveManifestPath returns the path to the manifest written next to the archive of a job
+func GetArchiveManifestPath(archive string) string {
+	return archive + archiveManifestSuffix
+}
+
+// zstdWriter compresses the data written to it with the zstd command
+type zstdWriter struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr bytes.Buffer
+}
+
+func (w *zstdWriter) Write(p []byte) (int, error) {
+	return w.stdin.Write(p)
+}
+
+// Close waits for zstd to compress all the data
+func (w *zstdWriter) Close() error {
+	err := w.stdin.Close()
+	errWait := w.cmd.Wait()
+	if errWait != nil {
+		return fmt.Errorf("zstd failed: %w (stderr: %s)", errWait, w.stderr.String())
+	}
+	return err
+}
+
+// newCompressor returns a writer compressing data to a file in a given format
+func newCompressor(format string, f *os.File) (io.WriteCloser, error) {
+	switch format {
+	case "", job.ArchiveGzip:
+		return gzip.NewWriter(f), nil
+	case job.ArchiveZstd:
+		zstdPath, err := exec.LookPath("zstd")
+		if err != nil {
+			return nil, fmt.Errorf("zstd not found: %w", err)
+		}
+		w := new(zstdWriter)
+		w.cmd = exec.Command(zstdPath, "-q", "-c")
+		w.cmd.Stdout = f
+		w.cmd.Stderr = &w.stderr
+		w.stdin, err = w.cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		err = w.cmd.Start()
+		if err != nil {
+			return nil, fmt.Errorf("unable to start zstd: %w", err)
+		}
+		return w, nil
+	}
+	return nil, fmt.Errorf("unsupported archive format: %s", format)
+}
+
+// getArchiveExtension returns the extension of archives in a given format
+func getArchiveExtension(format string) string {
+	if format == job.ArchiveZstd {
+		return ".tar.zst"
+	}
+	return ".tar.gz"
+}
+
+// getArchiveItems returns the files of a completed job to archive: metadata, batch script, stdout, stderr and artifacts
+func (jobmgr *JM) getArchiveItems(j *job.Job, sysCfg *sys.Config) ([]archiveItem, error) {
+	var items []archiveItem
+	metadata, err := json.MarshalIndent(jobmgr.newStoreRecord(j, sysCfg), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode the metadata of job %s: %w", j.Name, err)
+	}
+	items = append(items, archiveItem{entry: ArchiveEntry{Name: "metadata.json", Kind: EntryMetadata}, content: metadata})
+
+	if j.BatchScript != "" {
+		items = append(items, archiveItem{entry: ArchiveEntry{Name: filepath.Base(j.BatchScript), Kind: EntryBatchScript, Source: j.BatchScript}})
+	}
+
+	outputFile, errorFile := jobmgr.getOutputFiles(j, sysCfg)
+	if outputFile != "" {
+		items = append(items, archiveItem{entry: ArchiveEntry{Name: filepath.Base(outputFile), Kind: EntryStdout, Source: outputFile}})
+		items = append(items, archiveItem{entry: ArchiveEntry{Name: filepath.Base(errorFile), Kind: EntryStderr, Source: errorFile}})
+	} else {
+		// The output of jobs run from the local node is in memory
+		items = append(items, archiveItem{entry: ArchiveEntry{Name: "stdout", Kind: EntryStdout}, content: j.OutBuffer.Bytes()})
+		items = append(items, archiveItem{entry: ArchiveEntry{Name: "stderr", Kind: EntryStderr}, content: j.ErrBuffer.Bytes()})
+	}
+
+	if len(j.Artifacts) > 0 {
+		artifacts, err := jobmgr.CollectArtifacts(j, sysCfg)
+		if err != nil {
+			log.Printf("[WARN] unable to collect the artifacts of job %s: %s", j.Name, err)
+		}
+		outputDir := getJobOutputDir(j, sysCfg)
+		for _, path := range artifacts {
+			name, err := filepath.Rel(outputDir, path)
+			if err != nil {
+				name = filepath.Base(path)
+			}
+			items = append(items, archiveItem{entry: ArchiveEntry{Name: filepath.Join("artifacts", name), Kind: EntryArtifact, Source: path}})
+		}
+	}
+	return items, nil
+}
+
+// addArchiveItem adds a file to an archive, with the size that was checked against the limits of the archive
+func addArchiveItem(tw *tar.Writer, topDir string, item *archiveItem, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    filepath.ToSlash(filepath.Join(topDir, item.entry.Name)),
+		Mode:    0644,
+		Size:    item.entry.Size,
+		ModTime: modTime,
+	}
+	err := tw.WriteHeader(hdr)
+	if err != nil {
+		return err
+	}
+	if item.entry.Source == "" {
+		_, err = tw.Write(item.content)
+		return err
+	}
+	f, err := os.Open(item.entry.Source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// The file may still grow, e.g., a log file, or be truncated, in which case it is padded with zeros
+	// to the size in the header
+	n, err := io.CopyN(tw, f, item.entry.Size)
+	if err == io.EOF {
+		item.entry.Padded = item.entry.Size - n
+		_, err = io.CopyN(tw, zeroReader{}, item.entry.Padded)
+	}
+	return err
+}
+
+// Archive gathers the files of a completed job, i.e., its batch script, stdout, stderr, metadata and artifacts,
+// in a compressed tarball as specified by Job.Archive (default settings if not set), along with a manifest listing
+// what was captured. The manifest is also written next to the archive. The artifacts of the job are collected
+// in its output directory first. The path to the archive is recorded in the job store when jobs are recorded.
+func (jobmgr *JM) Archive(j *job.Job, sysCfg *sys.Config) (*ArchiveManifest, error) {
+	spec := j.Archive
+	if spec == nil {
+		spec = new(job.ArchiveSpec)
+	}
+	dir := spec.Dir
+	if dir == "" {
+		if sysCfg.Persistent == "" {
+			return nil, fmt.Errorf("unable to archive job %s: no persistent directory", j.Name)
+		}
+		dir = filepath.Join(sysCfg.Persistent, archivesDir)
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %s: %w", dir, err)
+	}
+
+	name := getJobOutFilenamePrefix(j)
+	if name == "" {
+		name = j.Name
+	}
+	if j.ID != 0 {
+		name += "-" + strconv.Itoa(j.ID)
+	}
+	format := spec.Format
+	if format == "" {
+		format = job.ArchiveGzip
+	}
+	manifest := &ArchiveManifest{
+		Job:     j.Name,
+		JobID:   j.ID,
+		Archive: filepath.Join(dir, name+getArchiveExtension(format)),
+		Format:  format,
+		Created: time.Now(),
+	}
+
+	items, err := jobmgr.getArchiveItems(j, sysCfg)
+	if err != nil {
+		return nil, err
+	}
+	for idx := range items {
+		e := &items[idx].entry
+		e.Size = int64(len(items[idx].content))
+		if e.Source != "" {
+			info, err := os.Stat(e.Source)
+			if err != nil {
+				e.Skipped = "not found"
+				continue
+			}
+			e.Size = info.Size()
+		}
+		switch {
+		case spec.MaxFileSize > 0 && e.Size > spec.MaxFileSize:
+			e.Skipped = fmt.Sprintf("larger than %d bytes", spec.MaxFileSize)
+		case spec.MaxSize > 0 && manifest.Size+e.Size > spec.MaxSize:
+			e.Skipped = fmt.Sprintf("archive larger than %d bytes", spec.MaxSize)
+		default:
+			manifest.Size += e.Size
+		}
+	}
+
+	// The archive is complete once renamed
+	tmpPath := manifest.Archive + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %s: %w", tmpPath, err)
+	}
+	defer os.Remove(tmpPath)
+	defer f.Close()
+	cw, err := newCompressor(format, f)
+	if err != nil {
+		return nil, err
+	}
+	tw := tar.NewWriter(cw)
+	for idx := range items {
+		if items[idx].entry.Skipped != "" {
+			continue
+		}
+		err = addArchiveItem(tw, name, &items[idx], manifest.Created)
+		if err != nil {
+			cw.Close()
+			return nil, fmt.Errorf("unable to archive %s: %w", items[idx].entry.Name, err)
+		}
+	}
+	// The manifest is the last file of the archive, it records the files that were padded
+	for _, item := range items {
+		manifest.Entries = append(manifest.Entries, item.entry)
+	}
+	manifestContent, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		cw.Close()
+		return nil, fmt.Errorf("unable to encode the manifest of job %s: %w", j.Name, err)
+	}
+	manifestItem := archiveItem{entry: ArchiveEntry{Name: archiveManifestName, Size: int64(len(manifestContent))}, content: manifestContent}
+	err = addArchiveItem(tw, name, &manifestItem, manifest.Created)
+	if err == nil {
+		err = tw.Close()
+	}
+	errClose := cw.Close()
+	if err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to write %s: %w", tmpPath, err)
+	}
+	err = os.Rename(tmpPath, manifest.Archive)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %s: %w", manifest.Archive, err)
+	}
+
+	manifestPath := GetArchiveManifestPath(manifest.Archive)
+	err = os.WriteFile(manifestPath, manifestContent, 0644)
+	if err != nil {
+		return manifest, fmt.Errorf("unable to write %s: %w", manifestPath, err)
+	}
+	jobmgr.recordArchive(j, sysCfg, manifest.Archive)
+	return manifest, nil
+}
+
+// completeJob records the result of a completed job in the job store, then collects its artifacts and archives
+// its files, as specified by the job, unless the job could not be submitted. Failing to do so does not make the
+// job fail.
+func (jobmgr *JM) completeJob(j *job.Job, sysCfg *sys.Config, res *job.Result) {
+	jobmgr.recordResult(j, sysCfg, res)
+	if res.Failure == job.FailureSubmission {
+		return
+	}
+	if j.Archive == nil {
+		jobmgr.collectArtifacts(j, sysCfg)
+		return
+	}
+	manifest, err := jobmgr.Archive(j, sysCfg)
+	if err != nil {
+		log.Printf("[WARN] unable to archive job %s: %s", j.Name, err)
+		return
+	}
+	log.Printf("-> Job %s archived in %s", j.Name, manifest.Archive)
+}
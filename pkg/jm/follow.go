@@ -0,0 +1,142 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package jm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
+	"github.com/gvallee/go_hpcjob/pkg/hpcjob"
+)
+
+// defaultFollowInterval is how often files are checked for new content when following the output of a job
+const defaultFollowInterval = time.Second
+
+// readNew copies to w the content of a file written since a given offset and returns the new offset.
+// A file that does not exist yet is not an error; a file that was truncated is read from the beginning.
+func readNew(path string, offset int64, w io.Writer) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return offset, nil
+		}
+		return offset, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return offset, err
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if info.Size() == offset {
+		return offset, nil
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return offset, err
+	}
+	n, err := io.Copy(w, f)
+	return offset + n, err
+}
+
+// FollowFile copies the content of a file to w as it grows, until ctx is done. The file does not need to exist
+// when following starts. The content written before ctx is done is always copied.
+func FollowFile(ctx context.Context, path string, w io.Writer, pollInterval time.Duration) error {
+	if pollInterval <= 0 {
+		pollInterval = defaultFollowInterval
+	}
+	var offset int64
+	var err error
+	for {
+		offset, err = readNew(path, offset, w)
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %w", path, err)
+		}
+		select {
+		case <-ctx.Done():
+			_, err = readNew(path, offset, w)
+			if err != nil {
+				return fmt.Errorf("unable to read %s: %w", path, err)
+			}
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// waitForCompletion waits for a job handled by the job manager to complete, or for ctx to be done
+func (jobmgr *JM) waitForCompletion(ctx context.Context, j *job.Job, pollInterval time.Duration) error {
+	for {
+		statuses, err := jobmgr.JobStatus([]int{j.ID})
+		if err != nil {
+			return fmt.Errorf("unable to get the status of job %d: %w", j.ID, err)
+		}
+		if len(statuses) != 1 {
+			return fmt.Errorf("unable to get the status of job %d", j.ID)
+		}
+		switch statuses[0].Code {
+		case hpcjob.JOB_STATUS_DONE, hpcjob.JOB_STATUS_STOP:
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Follow copies the output and stderr of a batch job to stdout and stderr as the job manager writes them, until
+// the job completes or ctx is done. The output files do not need to exist yet, e.g., when the job is pending.
+// The status of the job is queried every pollInterval (30 seconds if not set).
+func (jobmgr *JM) Follow(ctx context.Context, j *job.Job, sysCfg *sys.Config, stdout io.Writer, stderr io.Writer, pollInterval time.Duration) error {
+	outputFile, errorFile := jobmgr.getOutputFiles(j, sysCfg)
+	if outputFile == "" {
+		return fmt.Errorf("unable to follow job %s: the output files are unknown", j.Name)
+	}
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	followCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for idx, f := range []struct {
+		path string
+		w    io.Writer
+	}{{outputFile, stdout}, {errorFile, stderr}} {
+		if f.w == nil || f.path == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(idx int, path string, w io.Writer) {
+			defer wg.Done()
+			errs[idx] = FollowFile(followCtx, path, w, defaultFollowInterval)
+		}(idx, f.path, f.w)
+	}
+
+	err := jobmgr.waitForCompletion(followCtx, j, pollInterval)
+	stop()
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
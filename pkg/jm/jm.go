@@ -7,13 +7,18 @@
 package jm
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/gvallee/go_exec/pkg/advexec"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/jobstore"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
 	"github.com/gvallee/go_hpcjob/pkg/hpcjob"
 	"github.com/gvallee/go_util/pkg/util"
@@ -61,6 +66,12 @@ type NumJobsFn func(jobmgr *JM, partition string, user string) (int, error)
 // PostJobFn is a "function pointer" that lets us update results once the job completes. By default jobs are blocking, in which case this does not need to be used.
 type PostJobFn func(cmdRes *advexec.Result, j *job.Job, sysCfg *sys.Config) advexec.Result
 
+// ReattachFn is a "function pointer" that lets us rebuild a job submitted by another process
+type ReattachFn func(jobmgr *JM, jobID int, sysCfg *sys.Config) (*job.Job, error)
+
+// AccountingFn is a "function pointer" that lets us get the accounting record of a completed job
+type AccountingFn func(jobmgr *JM, j *job.Job) (*job.Accounting, error)
+
 // JM is the structure representing a specific JM
 type JM struct {
 	// ID identifies which job manager has been detected on the system
@@ -76,6 +87,10 @@ type JM struct {
 
 	postRunJM PostJobFn
 
+	accountingJM AccountingFn
+
+	reattachJM ReattachFn
+
 	BinPath string
 
 	CmdArgs []string
@@ -130,6 +145,14 @@ func getBatchScriptPath(j *job.Job, sysCfg *sys.Config, batchScriptFilenamePrefi
 	return "", fmt.Errorf("unable to determine the path to use for the batch script")
 }
 
+// getOutputFiles returns the paths to the files with stdout and stderr of a job, for job managers that write them
+func (jobmgr *JM) getOutputFiles(j *job.Job, sysCfg *sys.Config) (string, string) {
+	if !jobmgr.isBatch() || (j.ExecutionTimestamp == "" && j.OutputFile == "") {
+		return "", ""
+	}
+	return getJobOutputFilePath(j, sysCfg), getJobErrorFilePath(j, sysCfg)
+}
+
 // TempFile creates a temporary file that is used to store a batch script
 func TempFile(j *job.Job, sysCfg *sys.Config) error {
 	j.SetTimestamp()
@@ -141,13 +164,13 @@ func TempFile(j *job.Job, sysCfg *sys.Config) error {
 		return err
 	}
 
-	j.CleanUp = func(...interface{}) error {
+	j.AddCleanUp(func(...interface{}) error {
 		err := os.RemoveAll(j.BatchScript)
 		if err != nil {
 			return fmt.Errorf("unable to delete %s: %s", j.BatchScript, err)
 		}
 		return nil
-	}
+	})
 
 	return nil
 }
@@ -158,8 +181,36 @@ func (jobmgr *JM) Load(sysCfg *sys.Config) error {
 }
 
 // Submit executes a job with a job manager that was previously detected and loaded
+// The software environment of the job is validated before submitting the job.
+// The job is recorded in the job store when sysCfg.JobStoreDir is set.
+// The artifacts of a blocking job are collected in its output directory and its files archived, if requested, once completed.
 func (jobmgr *JM) Submit(j *job.Job, sysCfg *sys.Config) advexec.Result {
-	return jobmgr.submitJM(j, jobmgr, sysCfg)
+	// The job may have been submitted before
+	j.SetAccounting(nil)
+	j.StoreKey = ""
+	err := j.GetSoftwareEnv().Validate()
+	if err != nil {
+		var res advexec.Result
+		res.Err = fmt.Errorf("invalid software environment: %s", err)
+		return res
+	}
+	if sysCfg.JobStoreDir != "" {
+		j.StoreKey = jobstore.NewKey()
+	}
+	start := time.Now()
+	res := jobmgr.submitJM(j, jobmgr, sysCfg)
+	if jobmgr.isBatch() && j.ID != 0 {
+		// Update the metadata of the job with its ID
+		err := jobmgr.writeJobMetadata(j, sysCfg)
+		if err != nil {
+			log.Printf("[WARN] %s", err)
+		}
+	}
+	jobmgr.recordSubmission(j, sysCfg, start, &res)
+	if !j.NonBlocking {
+		jobmgr.completeJob(j, sysCfg, jobmgr.GetResult(j, &res))
+	}
+	return res
 }
 
 func (jobmgr *JM) JobStatus(jobIDs []int) ([]hpcjob.Status, error) {
@@ -184,3 +235,61 @@ func (jobmgr *JM) PostRun(cmdRes *advexec.Result, j *job.Job, sysCfg *sys.Config
 	}
 	return jobmgr.postRunJM(cmdRes, j, sysCfg)
 }
+
+// Accounting returns the accounting record of a completed job (state, exit code, elapsed time, nodes, etc.)
+func (jobmgr *JM) Accounting(j *job.Job) (*job.Accounting, error) {
+	if jobmgr.accountingJM == nil {
+		return nil, fmt.Errorf("not implemented")
+	}
+	return jobmgr.accountingJM(jobmgr, j)
+}
+
+// isBatch checks whether the job manager submits jobs to a batch scheduler
+func (jobmgr *JM) isBatch() bool {
+	return jobmgr.ID == SlurmID || jobmgr.ID == IntelSlurmID
+}
+
+// GetResult returns the structured result of a job based on the result of its submission and, when
+// available, its accounting record. It lets callers distinguish a failure of the application from a
+// job terminated by the job manager and from a job that could not be submitted.
+func (jobmgr *JM) GetResult(j *job.Job, execRes *advexec.Result) *job.Result {
+	r := &job.Result{Err: execRes.Err}
+
+	// A batch job that could not be submitted does not have an ID
+	if execRes.Err != nil && jobmgr.isBatch() && j.ID == 0 {
+		r.Failure = job.FailureSubmission
+		return r
+	}
+
+	a, err := jobmgr.Accounting(j)
+	if err == nil {
+		r.Accounting = a
+		r.State = a.State
+		r.ExitCode = a.ExitCode
+		r.Signal = a.Signal
+		r.Failure = job.ClassifyFailure(r.State, r.ExitCode, r.Signal)
+		return r
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case execRes.Err == nil && j.NonBlocking:
+		// The job was submitted and is handled by the job manager
+		r.Failure = job.FailurePending
+		return r
+	case execRes.Err == nil:
+		r.State = job.StateCompleted
+	case errors.As(execRes.Err, &exitErr):
+		r.State = job.StateFailed
+		r.ExitCode = exitErr.ExitCode()
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			r.Signal = int(ws.Signal())
+		}
+	default:
+		// The command could not be started
+		r.Failure = job.FailureSubmission
+		return r
+	}
+	r.Failure = job.ClassifyFailure(r.State, r.ExitCode, r.Signal)
+	return r
+}
@@ -62,6 +62,8 @@ func IntelSlurmDetect() (bool, JM) {
 	jm.jobStatusJM = intelSlurmGetJobStatus
 	jm.numJobsJM = intelSlurmGetNumJobs
 	jm.postRunJM = slurmPostJob
+	jm.accountingJM = slurmAccounting
+	jm.reattachJM = slurmReattach
 
 	return true, jm
 }
@@ -97,16 +99,8 @@ func intelSlurmSubmit(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result
 
 	cmd.BinPath = jobmgr.BinPath
 	cmd.ExecDir = j.RunDir
-	// We want the default to be blocking sbatch but users can request non-blocking
-	if !j.NonBlocking {
-		jobmgr.CmdArgs = append(jobmgr.CmdArgs, "-W")
-	}
-
-	if len(jobmgr.CmdArgs) > 0 {
-		cmd.CmdArgs = append(cmd.CmdArgs, jobmgr.CmdArgs...)
-	}
-	//cmd.CmdArgs = append(cmd.CmdArgs, j.BatchScript)
-	cmd.CmdArgs = []string{j.BatchScript}
+	cmd.CmdArgs = getSbatchArgs(jobmgr, j)
+	cmd.Timeout = getSbatchTimeout(j)
 
 	j.SetOutputFn(slurmGetOutput)
 	j.SetErrorFn(slurmGetError)
@@ -116,6 +110,12 @@ func intelSlurmSubmit(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result
 		return resExec
 	}
 
+	// Let other processes reattach to the job, even if we do not get its ID
+	err = jobmgr.writeJobMetadata(j, sysCfg)
+	if err != nil {
+		log.Printf("[WARN] %s", err)
+	}
+
 	cmdRes := cmd.Run()
 	if strings.HasPrefix(cmdRes.Stdout, slurmJobIDPrefix) {
 		jobIDStr := strings.TrimPrefix(cmdRes.Stdout, slurmJobIDPrefix)
@@ -7,10 +7,28 @@
 package jm
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/gvallee/go_exec/pkg/advexec"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/jobstore"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mpi"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
+	"github.com/gvallee/go_hpcjob/pkg/hpcjob"
 	"github.com/gvallee/go_util/pkg/util"
 )
 
@@ -41,3 +59,598 @@ func TestTempFile(t *testing.T) {
 		t.Fatalf("temporary file %s still exists even after cleanup", j.BatchScript)
 	}
 }
+
+func TestPrepareMPISubmitEnv(t *testing.T) {
+	var j job.Job
+	var cmd advexec.Advcmd
+	sysCfg := sys.Config{ScratchDir: t.TempDir()}
+	j.App.BinPath = "/bin/true"
+	j.MPICfg = &mpi.Config{Implem: implem.Info{ID: implem.MPICH, InstallDir: "/opt/mpich"}}
+	j.CustomEnv = map[string]string{"FOO": "1"}
+	j.EnvMode = job.EnvClear
+
+	err := prepareMPISubmit(&cmd, &j, &sysCfg, j.GetNetworkConfig())
+	if err != nil {
+		t.Fatalf("prepareMPISubmit() failed: %s", err)
+	}
+	if !strings.Contains(strings.Join(cmd.CmdArgs, " "), "-genv FOO=1") {
+		t.Fatalf("CustomEnv is not exported to the ranks: %v", cmd.CmdArgs)
+	}
+	expected := "PATH=/opt/mpich/bin:/usr/bin:/bin LD_LIBRARY_PATH=/opt/mpich/lib FOO=1"
+	if strings.Join(cmd.Env, " ") != expected {
+		t.Fatalf("invalid environment: %q instead of %q", strings.Join(cmd.Env, " "), expected)
+	}
+}
+
+func TestPrepareMPISubmitParamsAsEnv(t *testing.T) {
+	var j job.Job
+	var cmd advexec.Advcmd
+	sysCfg := sys.Config{ScratchDir: t.TempDir()}
+	j.App.BinPath = "/bin/true"
+	j.MPICfg = &mpi.Config{Implem: implem.Info{ID: implem.OMPI, InstallDir: "/opt/openmpi"}}
+	j.MPICfg.UserMpirunArgs = []string{"--mca", "btl", "self,tcp"}
+	j.MPICfg.ParamsAsEnv = true
+	j.CustomEnv = map[string]string{"FOO": "1"}
+	j.EnvMode = job.EnvClear
+	j.Network.Device = "mlx5_0"
+
+	err := prepareMPISubmit(&cmd, &j, &sysCfg, j.GetNetworkConfig())
+	if err != nil {
+		t.Fatalf("prepareMPISubmit() failed: %s", err)
+	}
+	args := strings.Join(cmd.CmdArgs, " ")
+	if strings.Contains(args, "--mca") {
+		t.Fatalf("parameters passed as mpirun arguments: %v", cmd.CmdArgs)
+	}
+	// mpirun forwards the MCA parameters of its environment but not the other variables
+	if !strings.Contains(args, "-x FOO") || !strings.Contains(args, "-x UCX_NET_DEVICES") {
+		t.Fatalf("the environment variables are not forwarded to the ranks: %v", cmd.CmdArgs)
+	}
+	for _, expected := range []string{"OMPI_MCA_btl=self,tcp", "OMPI_MCA_pml=ucx", "FOO=1", "UCX_NET_DEVICES=mlx5_0"} {
+		found := false
+		for _, e := range cmd.Env {
+			found = found || e == expected
+		}
+		if !found {
+			t.Fatalf("%s is not set in the environment of mpirun: %v", expected, cmd.Env)
+		}
+	}
+}
+
+func TestPrepareMPISubmitAppCommand(t *testing.T) {
+	var j job.Job
+	var cmd advexec.Advcmd
+	sysCfg := sys.Config{ScratchDir: t.TempDir()}
+	j.App.BinPath = "/bin/echo"
+	j.App.BinArgs = []string{"hello", "world"}
+	j.NP = 2
+	j.MPICfg = &mpi.Config{Implem: implem.Info{ID: implem.OMPI, InstallDir: "/opt/openmpi"}}
+
+	err := prepareMPISubmit(&cmd, &j, &sysCfg, j.GetNetworkConfig())
+	if err != nil {
+		t.Fatalf("prepareMPISubmit() failed: %s", err)
+	}
+	if cmd.BinPath != "/opt/openmpi/bin/mpirun" {
+		t.Fatalf("invalid launcher: %s", cmd.BinPath)
+	}
+	args := strings.Join(cmd.CmdArgs, " ")
+	if !strings.HasPrefix(args, "-np 2 ") || !strings.HasSuffix(args, " /bin/echo hello world") {
+		t.Fatalf("the application command does not terminate the mpirun command: %v", cmd.CmdArgs)
+	}
+}
+
+func TestGetResult(t *testing.T) {
+	_, native := NativeDetect()
+	slurmJM := JM{ID: SlurmID, accountingJM: slurmAccounting}
+
+	failedCmd := exec.Command("/bin/sh", "-c", "exit 2")
+	exitErr := failedCmd.Run()
+	if exitErr == nil {
+		t.Fatalf("the command did not fail")
+	}
+
+	var j job.Job
+	tests := []struct {
+		name       string
+		jobmgr     JM
+		accounting *job.Accounting
+		err        error
+		failure    string
+		exitCode   int
+	}{
+		{name: "native success", jobmgr: native, failure: job.FailureNone},
+		{name: "native not started", jobmgr: native, err: fmt.Errorf("mpirun not found"), failure: job.FailureSubmission},
+		{name: "native app failure", jobmgr: native, err: exitErr, failure: job.FailureApplication, exitCode: 2},
+		{name: "native accounting", jobmgr: native, err: exitErr, accounting: &job.Accounting{State: job.StateFailed, ExitCode: 3}, failure: job.FailureApplication, exitCode: 3},
+		{name: "timeout", jobmgr: native, err: exitErr, accounting: &job.Accounting{State: job.StateTimeout, Signal: 15}, failure: job.FailureScheduler},
+		{name: "sbatch failure", jobmgr: slurmJM, err: exitErr, failure: job.FailureSubmission},
+		{name: "still pending", jobmgr: native, accounting: &job.Accounting{State: "PENDING"}, failure: job.FailurePending},
+	}
+	for _, tt := range tests {
+		j.SetAccounting(tt.accounting)
+		res := tt.jobmgr.GetResult(&j, &advexec.Result{Err: tt.err})
+		if res.Failure != tt.failure || res.ExitCode != tt.exitCode {
+			t.Fatalf("%s: GetResult() returned %+v", tt.name, res)
+		}
+		if res.Success() != (tt.failure == job.FailureNone && tt.err == nil) || res.IsFinal() == (tt.failure == job.FailurePending) {
+			t.Fatalf("%s: invalid success status: %+v", tt.name, res)
+		}
+	}
+}
+
+func TestQueue(t *testing.T) {
+	var mutex sync.Mutex
+	active := 0
+	maxActive := 0
+	jobmgr := JM{ID: "test"}
+	jobmgr.submitJM = func(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result {
+		var res advexec.Result
+		mutex.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mutex.Lock()
+		active--
+		mutex.Unlock()
+		if j.Name == "fail" {
+			res.Err = fmt.Errorf("submission failed")
+		}
+		return res
+	}
+	jobmgr.numJobsJM = func(jobmgr *JM, partition string, user string) (int, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return active, nil
+	}
+
+	events := make(map[string]int)
+	q := Queue{
+		MaxJobs:      2,
+		User:         "test",
+		PollInterval: time.Millisecond,
+		OnProgress: func(p *Progress) {
+			events[p.Event]++
+			if p.Total != 5 || p.Pending+p.Running+p.Completed != p.Total {
+				t.Errorf("invalid progress: %+v", p)
+			}
+		},
+	}
+	for _, name := range []string{"a", "b", "fail", "c", "d"} {
+		q.Add(&job.Job{Name: name})
+	}
+	var sysCfg sys.Config
+	err := q.Run(&jobmgr, &sysCfg)
+	if err != nil {
+		t.Fatalf("Run() failed: %s", err)
+	}
+	if maxActive > 2 {
+		t.Fatalf("%d jobs were running at the same time", maxActive)
+	}
+	if events[EventSubmitted] != 5 || events[EventCompleted] != 4 || events[EventFailed] != 1 {
+		t.Fatalf("invalid progress events: %v", events)
+	}
+	if q.Entries()[2].Result.Failure != job.FailureSubmission || !q.Entries()[3].Result.Success() {
+		t.Fatalf("invalid results: %+v, %+v", q.Entries()[2].Result, q.Entries()[3].Result)
+	}
+}
+
+func TestSubmitWithRetry(t *testing.T) {
+	exitErr := exec.Command("/bin/sh", "-c", "exit 2").Run()
+	if exitErr == nil {
+		t.Fatalf("the command did not fail")
+	}
+	jobmgr := JM{ID: "test"}
+	numSubmit := 0
+	jobmgr.submitJM = func(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result {
+		var res advexec.Result
+		numSubmit++
+		if j.Attempt != numSubmit {
+			res.Err = fmt.Errorf("invalid attempt number: %d", j.Attempt)
+			return res
+		}
+		if j.Name == "transient" && j.Attempt < 3 {
+			res.Err = fmt.Errorf("socket timed out")
+		}
+		if j.Name == "app" {
+			res.Err = exitErr
+		}
+		return res
+	}
+
+	var sysCfg sys.Config
+	j := job.Job{Name: "transient", Retry: &job.RetryPolicy{MaxAttempts: 5, Backoff: time.Millisecond}}
+	r, _ := jobmgr.SubmitWithRetry(&j, &sysCfg)
+	if !r.Success() || len(r.Attempts) != 3 || r.Attempts[0].Result.Failure != job.FailureSubmission {
+		t.Fatalf("SubmitWithRetry() returned %+v", r)
+	}
+
+	numSubmit = 0
+	j = job.Job{Name: "transient", Retry: &job.RetryPolicy{MaxAttempts: 2}}
+	r, _ = jobmgr.SubmitWithRetry(&j, &sysCfg)
+	if r.Success() || len(r.Attempts) != 2 || numSubmit != 2 {
+		t.Fatalf("SubmitWithRetry() returned %+v after %d attempts", r, numSubmit)
+	}
+
+	// Failures of the application are not retried by default
+	numSubmit = 0
+	j = job.Job{Name: "app", Retry: &job.RetryPolicy{MaxAttempts: 5}}
+	r, _ = jobmgr.SubmitWithRetry(&j, &sysCfg)
+	if r.Failure != job.FailureApplication || len(r.Attempts) != 1 {
+		t.Fatalf("SubmitWithRetry() returned %+v", r)
+	}
+}
+
+func TestJobStore(t *testing.T) {
+	jobmgr := JM{ID: SlurmID}
+	jobmgr.submitJM = func(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result {
+		j.ID = 42
+		j.ExecutionTimestamp = "250101120000"
+		return advexec.Result{}
+	}
+	sysCfg := sys.Config{JobStoreDir: t.TempDir()}
+	j := job.Job{Name: "test", NP: 2, RunDir: "/scratch"}
+	res, _ := jobmgr.SubmitWithRetry(&j, &sysCfg)
+	if !res.Success() || j.StoreKey == "" {
+		t.Fatalf("SubmitWithRetry() returned %+v (key: %q)", res, j.StoreKey)
+	}
+
+	s, err := jobstore.Open(sysCfg.JobStoreDir)
+	if err != nil {
+		t.Fatalf("unable to open the job store: %s", err)
+	}
+	r, err := s.FindByJobID(SlurmID, 42)
+	if err != nil {
+		t.Fatalf("the job is not recorded: %s", err)
+	}
+	if r.Key != j.StoreKey || r.State != job.StateCompleted || r.Spec.NP != 2 || r.OutputFile != "/scratch/test-250101120000.out" {
+		t.Fatalf("invalid record: %+v", r)
+	}
+
+	// The final state of a non-blocking job is recorded once it completed
+	jobmgr.jobStatusJM = func(jobmgr *JM, jobIDs []int) ([]hpcjob.Status, error) {
+		return []hpcjob.Status{hpcjob.StatusDone}, nil
+	}
+	jobmgr.accountingJM = func(jobmgr *JM, j *job.Job) (*job.Accounting, error) {
+		return &job.Accounting{JobID: j.ID, State: job.StateTimeout}, nil
+	}
+	j = job.Job{Name: "test", NonBlocking: true}
+	jobmgr.Submit(&j, &sysCfg)
+	r, err = s.Get(j.StoreKey)
+	if err != nil || r.State != jobstore.StateSubmitted {
+		t.Fatalf("invalid record of the non-blocking job: %+v, %v", r, err)
+	}
+	err = jobmgr.Wait(&j, &sysCfg, time.Millisecond)
+	if err != nil {
+		t.Fatalf("Wait() failed: %s", err)
+	}
+	r, err = s.Get(j.StoreKey)
+	if err != nil || r.State != job.StateTimeout || len(r.Transitions) != 2 {
+		t.Fatalf("the completion of the non-blocking job is not recorded: %+v, %v", r, err)
+	}
+}
+
+func TestRunAndAccountStreams(t *testing.T) {
+	var j job.Job
+	var stdout bytes.Buffer
+	var errLines []string
+	j.Stdout = &stdout
+	j.Stderr = job.NewLineWriter(func(line string) {
+		errLines = append(errLines, line)
+	})
+	cmd := advexec.Advcmd{BinPath: "/bin/sh", CmdArgs: []string{"-c", "echo hello; echo warning >&2; printf done >&2"}}
+	res := runAndAccount(&cmd, &j)
+	if res.Err != nil {
+		t.Fatalf("runAndAccount() failed: %s", res.Err)
+	}
+	if res.Stdout != "hello\n" || j.OutBuffer.String() != "hello\n" || stdout.String() != "hello\n" {
+		t.Fatalf("invalid output: %q, %q, %q", res.Stdout, j.OutBuffer.String(), stdout.String())
+	}
+	if res.Stderr != "warning\ndone" || strings.Join(errLines, "|") != "warning|done" {
+		t.Fatalf("invalid stderr: %q, %q", res.Stderr, errLines)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	runDir := t.TempDir()
+	j := job.Job{ID: 42, Name: "test", RunDir: runDir, ExecutionTimestamp: "250101120000"}
+	var sysCfg sys.Config
+	outputFile := getJobOutputFilePath(&j, &sysCfg)
+
+	numStatus := 0
+	jobmgr := JM{ID: SlurmID}
+	jobmgr.jobStatusJM = func(jobmgr *JM, jobIDs []int) ([]hpcjob.Status, error) {
+		numStatus++
+		switch numStatus {
+		case 1:
+			// The job is pending, its output file does not exist yet
+			return []hpcjob.Status{hpcjob.StatusQueued}, nil
+		case 2:
+			err := os.WriteFile(outputFile, []byte("line 1\n"), 0644)
+			return []hpcjob.Status{hpcjob.StatusRunning}, err
+		}
+		f, err := os.OpenFile(outputFile, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		_, err = f.WriteString("line 2\n")
+		return []hpcjob.Status{hpcjob.StatusDone}, err
+	}
+
+	var stdout bytes.Buffer
+	err := jobmgr.Follow(context.Background(), &j, &sysCfg, &stdout, nil, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Follow() failed: %s", err)
+	}
+	if stdout.String() != "line 1\nline 2\n" {
+		t.Fatalf("Follow() returned %q", stdout.String())
+	}
+}
+
+func TestOutputDir(t *testing.T) {
+	runDir := t.TempDir()
+	var j job.Job
+	j.Name = "test"
+	j.BatchScript = filepath.Join(runDir, "job.sh")
+	j.RunDir = runDir
+	j.ExecutionTimestamp = "250101120000"
+	j.MPICfg = &mpi.Config{Implem: implem.Info{ID: implem.OMPI, Version: "4.1.5"}}
+	j.OutputDir = "out/{name}-{mpi}"
+	j.OutputPattern = "{name}-{id}"
+	sysCfg := sys.Config{ScratchDir: runDir}
+
+	// The job ID is replaced by Slurm until the job is submitted
+	content, err := generateBatchScriptContent(&j, &sysCfg)
+	if err != nil {
+		t.Fatalf("generateBatchScriptContent() failed: %s", err)
+	}
+	outputDir := filepath.Join(runDir, "out", "test-openmpi4.1.5")
+	if !strings.Contains(content, "--output="+filepath.Join(outputDir, "test-%j.out")+"\n") || !util.PathExists(outputDir) {
+		t.Fatalf("invalid batch script:\n%s", content)
+	}
+	j.ID = 42
+	jobmgr := JM{ID: SlurmID}
+	outputFile, errorFile := jobmgr.getOutputFiles(&j, &sysCfg)
+	if outputFile != filepath.Join(outputDir, "test-42.out") || errorFile != filepath.Join(outputDir, "test-42.err") {
+		t.Fatalf("getOutputFiles() returned %s and %s", outputFile, errorFile)
+	}
+	err = os.WriteFile(outputFile, []byte("Hello"), 0644)
+	if err != nil {
+		t.Fatalf("unable to write %s: %s", outputFile, err)
+	}
+	if slurmGetOutput(&j, &sysCfg) != "Hello" {
+		t.Fatalf("unable to get the output of the job")
+	}
+
+	// Default output directory of the system
+	j.OutputDir = ""
+	j.OutputPattern = ""
+	sysCfg.OutputDir = filepath.Join(runDir, "results")
+	outputFile, _ = jobmgr.getOutputFiles(&j, &sysCfg)
+	if outputFile != filepath.Join(sysCfg.OutputDir, "test-250101120000-openmpi4.1.5", "test-250101120000-openmpi4.1.5.out") {
+		t.Fatalf("getOutputFiles() returned %s", outputFile)
+	}
+
+	j.OutputDir = "out/{id}"
+	err = prepareOutputDir(&j, &sysCfg)
+	if err == nil {
+		t.Fatalf("prepareOutputDir() succeeded with the job ID in the output directory")
+	}
+}
+
+func TestCollectArtifacts(t *testing.T) {
+	runDir := t.TempDir()
+	for _, path := range []string{"results/a.txt", "results/b.txt", "results/c.log", "trace/rank0/events"} {
+		path = filepath.Join(runDir, path)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatalf("unable to create %s: %s", filepath.Dir(path), err)
+		}
+		err = os.WriteFile(path, []byte(path), 0644)
+		if err != nil {
+			t.Fatalf("unable to write %s: %s", path, err)
+		}
+	}
+	var j job.Job
+	j.Name = "test"
+	j.RunDir = runDir
+	j.OutputDir = "out"
+	j.Artifacts = []string{"results/*.txt", "trace", "missing*"}
+	var sysCfg sys.Config
+	jobmgr := JM{ID: NativeID}
+
+	collected, err := jobmgr.CollectArtifacts(&j, &sysCfg)
+	if err == nil || !strings.Contains(err.Error(), "missing*") {
+		t.Fatalf("CollectArtifacts() did not report the missing artifact: %v", err)
+	}
+	outputDir := filepath.Join(runDir, "out")
+	expected := []string{
+		filepath.Join(outputDir, "results", "a.txt"),
+		filepath.Join(outputDir, "results", "b.txt"),
+		filepath.Join(outputDir, "trace", "rank0", "events"),
+	}
+	if strings.Join(collected, ",") != strings.Join(expected, ",") {
+		t.Fatalf("CollectArtifacts() returned %v instead of %v", collected, expected)
+	}
+	content, err := os.ReadFile(expected[2])
+	if err != nil || string(content) != filepath.Join(runDir, "trace", "rank0", "events") {
+		t.Fatalf("invalid artifact %s: %q (%v)", expected[2], content, err)
+	}
+}
+
+// readArchive returns the content of the files in a gzip archive, keyed by name
+func readArchive(t *testing.T, path string) map[string]string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unable to open %s: %s", path, err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("invalid archive %s: %s", path, err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid archive %s: %s", path, err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("unable to read %s from %s: %s", hdr.Name, path, err)
+		}
+		files[hdr.Name] = string(content)
+	}
+	return files
+}
+
+func TestAddArchiveItemTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.log")
+	err := os.WriteFile(path, []byte("hello"), 0644)
+	if err != nil {
+		t.Fatalf("unable to write %s: %s", path, err)
+	}
+
+	// The file was larger when its size was checked
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	item := archiveItem{entry: ArchiveEntry{Name: "job.log", Kind: EntryStdout, Source: path, Size: 8}}
+	err = addArchiveItem(tw, "test", &item, time.Now())
+	if err == nil {
+		err = tw.Close()
+	}
+	if err != nil {
+		t.Fatalf("addArchiveItem() failed: %s", err)
+	}
+	if item.entry.Padded != 3 {
+		t.Fatalf("the padding of the truncated file is not recorded: %+v", item.entry)
+	}
+	tr := tar.NewReader(&buf)
+	_, err = tr.Next()
+	if err != nil {
+		t.Fatalf("invalid archive: %s", err)
+	}
+	content, err := io.ReadAll(tr)
+	if err != nil || string(content) != "hello\x00\x00\x00" {
+		t.Fatalf("invalid content of the truncated file: %q (%v)", content, err)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	runDir := t.TempDir()
+	for name, content := range map[string]string{"job.sh": "#!/bin/sh\n", "result.txt": "42\n", "trace.bin": strings.Repeat("x", 1024)} {
+		err := os.WriteFile(filepath.Join(runDir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("unable to write %s: %s", name, err)
+		}
+	}
+	jobmgr := JM{ID: NativeID}
+	jobmgr.submitJM = func(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result {
+		j.ExecutionTimestamp = "250101120000"
+		j.OutBuffer.WriteString("hello\n")
+		return advexec.Result{Stdout: "hello\n"}
+	}
+	sysCfg := sys.Config{Persistent: t.TempDir(), JobStoreDir: t.TempDir()}
+	j := job.Job{
+		Name:        "test",
+		RunDir:      runDir,
+		BatchScript: filepath.Join(runDir, "job.sh"),
+		OutputDir:   "out",
+		Artifacts:   []string{"*.txt", "*.bin"},
+		Archive:     &job.ArchiveSpec{MaxFileSize: 512},
+	}
+
+	// The job is archived once completed
+	res := jobmgr.Submit(&j, &sysCfg)
+	if res.Err != nil {
+		t.Fatalf("Submit() failed: %s", res.Err)
+	}
+	archive := filepath.Join(sysCfg.Persistent, archivesDir, "test-250101120000.tar.gz")
+	files := readArchive(t, archive)
+	expected := map[string]string{
+		"test-250101120000/job.sh":               "#!/bin/sh\n",
+		"test-250101120000/stdout":               "hello\n",
+		"test-250101120000/stderr":               "",
+		"test-250101120000/artifacts/result.txt": "42\n",
+	}
+	for name, content := range expected {
+		if files[name] != content {
+			t.Fatalf("invalid content of %s in the archive: %q", name, files[name])
+		}
+	}
+	if _, ok := files["test-250101120000/artifacts/trace.bin"]; ok {
+		t.Fatalf("the artifact larger than the limit is in the archive")
+	}
+
+	content, err := os.ReadFile(GetArchiveManifestPath(archive))
+	if err != nil {
+		t.Fatalf("unable to read the manifest: %s", err)
+	}
+	if files["test-250101120000/"+archiveManifestName] != string(content) {
+		t.Fatalf("the manifests in and next to the archive differ")
+	}
+	var manifest ArchiveManifest
+	err = json.Unmarshal(content, &manifest)
+	if err != nil {
+		t.Fatalf("invalid manifest: %s", err)
+	}
+	if len(manifest.Entries) != 6 || manifest.Entries[0].Kind != EntryMetadata || manifest.Entries[5].Skipped == "" {
+		t.Fatalf("invalid manifest: %+v", manifest)
+	}
+	s, err := jobstore.Open(sysCfg.JobStoreDir)
+	if err != nil {
+		t.Fatalf("unable to open the job store: %s", err)
+	}
+	r, err := s.Get(j.StoreKey)
+	if err != nil || r.Archive != archive {
+		t.Fatalf("the archive is not recorded: %+v (%v)", r, err)
+	}
+
+	// Limit of the size of the archive
+	j.Archive = &job.ArchiveSpec{MaxSize: 16, Dir: t.TempDir()}
+	m, err := jobmgr.Archive(&j, &sysCfg)
+	if err != nil {
+		t.Fatalf("Archive() failed: %s", err)
+	}
+	// The metadata do not fit; the batch script, stdout and stderr do and fill the archive
+	for _, e := range m.Entries {
+		archived := e.Kind == EntryBatchScript || e.Kind == EntryStdout || e.Kind == EntryStderr
+		if archived != (e.Skipped == "") {
+			t.Fatalf("invalid manifest entry with a size limit: %+v", e)
+		}
+	}
+
+	// A job that could not be submitted is not archived
+	jobmgr.submitJM = func(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result {
+		return advexec.Result{Err: fmt.Errorf("unable to start the job")}
+	}
+	failed := job.Job{Name: "failed", RunDir: runDir, Archive: &job.ArchiveSpec{Dir: t.TempDir()}}
+	jobmgr.Submit(&failed, &sysCfg)
+	archives, err := os.ReadDir(failed.Archive.Dir)
+	if err != nil || len(archives) != 0 {
+		t.Fatalf("the job that could not be submitted is archived: %v (%v)", archives, err)
+	}
+
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not found")
+	}
+	j.Archive = &job.ArchiveSpec{Format: job.ArchiveZstd, Dir: t.TempDir()}
+	m, err = jobmgr.Archive(&j, &sysCfg)
+	if err != nil {
+		t.Fatalf("Archive() failed: %s", err)
+	}
+	if !strings.HasSuffix(m.Archive, ".tar.zst") {
+		t.Fatalf("invalid archive name: %s", m.Archive)
+	}
+	out, err := exec.Command("sh", "-c", "zstd -d -c "+m.Archive+" | tar tf -").Output()
+	if err != nil || !strings.Contains(string(out), "test-250101120000/"+archiveManifestName) {
+		t.Fatalf("invalid zstd archive: %s (%v)", out, err)
+	}
+}
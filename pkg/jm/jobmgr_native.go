@@ -8,6 +8,7 @@ package jm
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"strconv"
 
@@ -15,6 +16,8 @@ import (
 	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/network"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/mpi"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/nodelist"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/params"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
 )
 
@@ -32,6 +35,48 @@ func nativeGetError(j *job.Job, sysCfg *sys.Config) string {
 	return j.ErrBuffer.String()
 }
 
+// setupHostfile generates the hostfile for the nodes the job must run on, if any, and returns
+// the mpirun arguments to use it. The hostfile is removed when the job is cleaned up.
+func setupHostfile(j *job.Job, sysCfg *sys.Config) ([]string, error) {
+	nodes := j.Nodes
+	if len(nodes) == 0 && j.UseAllocation {
+		var err error
+		nodes, err = nodelist.FromAllocation()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the nodes of the allocation: %s", err)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+
+	dir := j.RunDir
+	if dir == "" {
+		dir = sysCfg.ScratchDir
+	}
+	f, err := os.CreateTemp(dir, "hostfile-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %s", err)
+	}
+	hostfile := f.Name()
+	f.Close()
+
+	args, err := mpi.WriteHostfile(&j.MPICfg.Implem, nodes, hostfile)
+	if err != nil {
+		os.Remove(hostfile)
+		return nil, err
+	}
+	j.Hostfile = hostfile
+	j.AddCleanUp(func(...interface{}) error {
+		err := os.RemoveAll(hostfile)
+		if err != nil {
+			return fmt.Errorf("unable to delete %s: %s", hostfile, err)
+		}
+		return nil
+	})
+	return args, nil
+}
+
 func prepareMPISubmit(cmd *advexec.Advcmd, j *job.Job, sysCfg *sys.Config, netCfg *network.Config) error {
 	var err error
 	cmd.BinPath = filepath.Join(j.MPICfg.Implem.InstallDir, "bin", "mpirun")
@@ -40,22 +85,60 @@ func prepareMPISubmit(cmd *advexec.Advcmd, j *job.Job, sysCfg *sys.Config, netCf
 		cmd.CmdArgs = append(cmd.CmdArgs, strconv.Itoa(j.NP))
 	}
 
-	mpirunArgs, err := mpi.GetMpirunArgs(&j.MPICfg.Implem, &j.App, sysCfg, netCfg, j.MPICfg.UserMpirunArgs)
+	// CustomEnv is exported to the remote ranks, along with the parameters set by the user
+	userParams := j.GetEnvParams()
+	err = userParams.Merge(j.MPICfg.Params)
+	if err != nil {
+		return fmt.Errorf("invalid MPI parameters: %s", err)
+	}
+	var mpirunArgs []string
+	var mpiParams *params.Set
+	if j.MPICfg.ParamsAsEnv {
+		mpirunArgs, mpiParams, err = mpi.GetMpirunArgsAndParams(&j.MPICfg.Implem, &j.App, sysCfg, netCfg, j.Mapping, userParams, j.MPICfg.UserMpirunArgs)
+	} else {
+		mpirunArgs, err = mpi.GetMpirunArgs(&j.MPICfg.Implem, &j.App, sysCfg, netCfg, j.Mapping, userParams, j.MPICfg.UserMpirunArgs)
+	}
 	if err != nil {
 		return fmt.Errorf("unable to get mpirun arguments: %s", err)
 	}
 	if len(mpirunArgs) > 0 {
 		cmd.CmdArgs = append(cmd.CmdArgs, mpirunArgs...)
 	}
+	cmd.CmdArgs = append(cmd.CmdArgs, mpi.GetEnvForwardArgs(&j.MPICfg.Implem, mpiParams)...)
+	cmd.CmdArgs = append(cmd.CmdArgs, mpi.GetLauncherArgs(&j.MPICfg.Implem, mpi.DetectResourceManager())...)
 
-	//newPath := getEnvPath(j.HostCfg, env)
-	//newLDPath := getEnvLDPath(j.HostCfg, env)
-	//log.Printf("-> PATH=%s", newPath)
-	//log.Printf("-> LD_LIBRARY_PATH=%s\n", newLDPath)
-	//log.Printf("Using %s as PATH\n", newPath)
-	//log.Printf("Using %s as LD_LIBRARY_PATH\n", newLDPath)
-	//cmd.Env = append([]string{"LD_LIBRARY_PATH=" + newLDPath}, os.Environ()...)
-	//cmd.Env = append([]string{"PATH=" + newPath}, os.Environ()...)
+	rankOutputArgs, err := getMpirunRankOutputArgs(j)
+	if err != nil {
+		return err
+	}
+	if dir := j.GetRankOutputDir(); j.PerRankOutput && dir != "" {
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return fmt.Errorf("unable to create %s: %s", dir, err)
+		}
+	}
+	cmd.CmdArgs = append(cmd.CmdArgs, rankOutputArgs...)
+
+	hostfileArgs, err := setupHostfile(j, sysCfg)
+	if err != nil {
+		return err
+	}
+	cmd.CmdArgs = append(cmd.CmdArgs, hostfileArgs...)
+
+	// The application command terminates the mpirun command
+	appArgs, err := j.GetAppArgs()
+	if err != nil {
+		return fmt.Errorf("unable to get the application command: %s", err)
+	}
+	cmd.CmdArgs = append(cmd.CmdArgs, appArgs...)
+
+	cmd.Env, err = j.GetEnv(os.Environ())
+	if err != nil {
+		return fmt.Errorf("unable to set the environment of the job: %s", err)
+	}
+	// With ParamsAsEnv, the parameters are set in the environment of mpirun; the variables that mpirun does not
+	// forward to the ranks by itself are forwarded with the arguments returned by GetEnvForwardArgs
+	cmd.Env = append(cmd.Env, mpiParams.Env()...)
 
 	return nil
 }
@@ -79,8 +162,7 @@ func nativeSubmit(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result {
 		return res
 	}
 
-	netCfg := new(network.Config)
-	netCfg.Device = j.Device
+	netCfg := j.GetNetworkConfig()
 
 	err := prepareMPISubmit(&cmd, j, sysCfg, netCfg)
 	if err != nil {
@@ -94,7 +176,7 @@ func nativeSubmit(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result {
 	if j.RunDir != "" {
 		cmd.ExecDir = j.RunDir
 	}
-	return cmd.Run()
+	return runAndAccount(&cmd, j)
 }
 
 func nativeLoad(jobmgr *JM, sysCfg *sys.Config) error {
@@ -111,6 +193,7 @@ func NativeDetect() (bool, JM) {
 	jm.loadJM = nativeLoad
 	jm.jobStatusJM = nil // Not implemented yet
 	jm.postRunJM = nil   // Not implemented yet
+	jm.accountingJM = localAccounting
 
 	// This is the default job manager, i.e., mpirun so we do not check anything, just return this component.
 	// If the component is selected and mpirun not correctly installed, the framework will pick it up later.
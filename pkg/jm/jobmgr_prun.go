@@ -9,6 +9,7 @@ package jm
 import (
 	"fmt"
 	"log"
+	"os"
 	"os/exec"
 
 	"github.com/gvallee/go_exec/pkg/advexec"
@@ -30,6 +31,18 @@ func prunGetError(j *job.Job, sysCfg *sys.Config) string {
 	return j.ErrBuffer.String()
 }
 
+// getPrunEnvArgs returns the prun arguments exporting the environment of the job to the ranks
+func getPrunEnvArgs(j *job.Job) []string {
+	args := []string{"-x", "PATH"}
+	if j.MPICfg != nil && j.MPICfg.Implem.InstallDir != "" {
+		args = append(args, "-x", "LD_LIBRARY_PATH")
+	}
+	for _, name := range j.CustomEnvNames() {
+		args = append(args, "-x", name+"="+j.CustomEnv[name])
+	}
+	return args
+}
+
 // PrunSubmit is the function to call to submit a job through the native job manager
 func PrunSubmit(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result {
 	var cmd advexec.Advcmd
@@ -48,26 +61,22 @@ func PrunSubmit(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result {
 	}
 
 	cmd.CmdArgs = append(cmd.CmdArgs, j.Args...)
-	cmd.CmdArgs = append(cmd.CmdArgs, "-x")
-	cmd.CmdArgs = append(cmd.CmdArgs, "PATH")
+	cmd.CmdArgs = append(cmd.CmdArgs, getPrunEnvArgs(j)...)
 	cmd.CmdArgs = append(cmd.CmdArgs, j.App.BinPath)
 	cmd.CmdArgs = append(cmd.CmdArgs, j.App.BinArgs...)
 	if j.RunDir != "" {
 		cmd.ExecDir = j.RunDir
 	}
 
-	//newPath := getEnvPath(j.HostCfg, env)
-	//newLDPath := getEnvLDPath(j.HostCfg, env)
-	//log.Printf("-> PATH=%s", newPath)
-	//log.Printf("-> LD_LIBRARY_PATH=%s\n", newLDPath)
-	//log.Printf("Using %s as PATH\n", newPath)
-	//log.Printf("Using %s as LD_LIBRARY_PATH\n", newLDPath)
-	//cmd.Env = append([]string{"LD_LIBRARY_PATH=" + newLDPath}, os.Environ()...)
-	//cmd.Env = append([]string{"PATH=" + newPath}, sycmd.Env...)
+	cmd.Env, err = j.GetEnv(os.Environ())
+	if err != nil {
+		res.Err = fmt.Errorf("unable to set the environment of the job: %s", err)
+		return res
+	}
 
 	j.SetOutputFn(prunGetOutput)
 	j.SetErrorFn(prunGetError)
-	return cmd.Run()
+	return runAndAccount(&cmd, j)
 }
 
 // PrunDetect is the function used by our job management framework to figure out if mpirun should be used directly.
@@ -86,6 +95,7 @@ func PrunDetect() (bool, JM) {
 	jm.submitJM = PrunSubmit
 	jm.jobStatusJM = nil // Not implemented yet
 	jm.postRunJM = nil   // Not implemented yet
+	jm.accountingJM = localAccounting
 
 	// This is the default job manager, i.e., mpirun so we do not check anything, just return this component.
 	// If the component is selected and mpirun not correctly installed, the framework will pick it up later.
@@ -16,12 +16,14 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gvallee/go_exec/pkg/advexec"
-	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/network"
 	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/openmpi"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mapping"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/mpi"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/params"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
 	"github.com/gvallee/go_hpcjob/pkg/hpcjob"
 	"github.com/gvallee/go_slurm/pkg/slurm"
@@ -30,6 +32,10 @@ import (
 
 const (
 	slurmJobIDPrefix = "Submitted batch job "
+
+	// blockingSubmitTimeout is the maximum time sbatch -W can run, i.e., the time a blocking job can spend in
+	// the queue and running; it is effectively unbounded since the limits are enforced by Slurm
+	blockingSubmitTimeout = 365 * 24 * time.Hour
 )
 
 func slurmGetJobStatus(jm *JM, jobIds []int) ([]hpcjob.Status, error) {
@@ -87,6 +93,8 @@ func SlurmDetect() (bool, JM) {
 	jm.jobStatusJM = slurmGetJobStatus
 	jm.numJobsJM = slurmGetNumJobs
 	jm.postRunJM = slurmPostJob
+	jm.accountingJM = slurmAccounting
+	jm.reattachJM = slurmReattach
 
 	return true, jm
 }
@@ -123,18 +131,48 @@ func getJobOutFilenamePrefix(j *job.Job) string {
 	if j.ExecutionTimestamp == "" {
 		return ""
 	}
+	prefix := j.Name + "-" + j.ExecutionTimestamp
 	if j.MPICfg != nil && j.MPICfg.Implem.ID != "" {
-		return j.Name + "-" + j.ExecutionTimestamp + "-" + j.MPICfg.Implem.ID + j.MPICfg.Implem.Version
+		prefix += "-" + j.MPICfg.Implem.ID + j.MPICfg.Implem.Version
+	}
+	// Each attempt has its own output files, even if submitted within the same second
+	if j.Attempt > 1 {
+		prefix += "-attempt" + strconv.Itoa(j.Attempt)
 	}
-	return j.Name + "-" + j.ExecutionTimestamp
+	return prefix
 }
 
+// getJobOutputFilePath returns the absolute path to the file where the job manager writes the output of a job
 func getJobOutputFilePath(j *job.Job, sysCfg *sys.Config) string {
-	return getJobOutFilenamePrefix(j) + ".out"
+	if j.OutputFile != "" {
+		return expandSlurmJobID(j.OutputFile, j)
+	}
+	return filepath.Join(getJobOutputDir(j, sysCfg), getJobOutputName(j)+".out")
 }
 
+// getJobErrorFilePath returns the absolute path to the file where the job manager writes stderr of a job
 func getJobErrorFilePath(j *job.Job, sysCfg *sys.Config) string {
-	return getJobOutFilenamePrefix(j) + ".err"
+	if j.ErrorFile != "" {
+		return expandSlurmJobID(j.ErrorFile, j)
+	}
+	return filepath.Join(getJobOutputDir(j, sysCfg), getJobOutputName(j)+".err")
+}
+
+// getSlurmExportArg returns the sbatch argument specifying which variables of the caller's environment
+// are propagated to the job, based on the environment mode of the job
+func getSlurmExportArg(j *job.Job) (string, error) {
+	switch j.EnvMode {
+	case "", job.EnvInherit:
+		return "", nil
+	case job.EnvClear:
+		return "--export=NONE", nil
+	case job.EnvAllowlist:
+		if len(j.EnvAllowlist) == 0 {
+			return "--export=NONE", nil
+		}
+		return "--export=" + strings.Join(j.EnvAllowlist, ","), nil
+	}
+	return "", fmt.Errorf("invalid environment mode: %s", j.EnvMode)
 }
 
 func generateBatchScriptContent(j *job.Job, sysCfg *sys.Config) (string, error) {
@@ -164,21 +202,34 @@ func generateBatchScriptContent(j *job.Job, sysCfg *sys.Config) (string, error)
 		}
 	*/
 
+	exportArg, err := getSlurmExportArg(j)
+	if err != nil {
+		return "", err
+	}
+	if exportArg != "" {
+		scriptText += slurm.ScriptCmdPrefix + " " + exportArg + "\n"
+	}
+
 	j.SetTimestamp()
+	err = prepareOutputDir(j, sysCfg)
+	if err != nil {
+		return "", err
+	}
 	scriptText += slurm.ScriptCmdPrefix + " --error=" + getJobErrorFilePath(j, sysCfg) + "\n"
 	scriptText += slurm.ScriptCmdPrefix + " --output=" + getJobOutputFilePath(j, sysCfg) + "\n"
 	scriptText += "\n"
 
-	if len(j.RequiredModules) > 0 {
-		scriptText += "\nmodule purge\nmodule load " + strings.Join(j.RequiredModules, " ") + "\n"
+	softEnvScript, err := j.GetSoftwareEnv().Script()
+	if err != nil {
+		return "", fmt.Errorf("invalid software environment: %s", err)
 	}
-
-	if j.CustomEnv != nil {
-		for envvar, val := range j.CustomEnv {
-			scriptText += fmt.Sprintf("export %s=%s\n", envvar, val)
-		}
+	if softEnvScript != "" {
+		scriptText += "\n" + softEnvScript
 	}
 
+	// The values are quoted for the shell, like the parameters of the MPI implementation
+	scriptText += j.GetEnvParams().Exports()
+
 	return scriptText, nil
 }
 
@@ -188,42 +239,59 @@ func setupMpiJob(j *job.Job, sysCfg *sys.Config) error {
 		return err
 	}
 
-	netCfg := new(network.Config)
-	netCfg.Device = j.Device
-
-	if j.CustomEnv != nil {
-		for envvar, val := range j.CustomEnv {
-			scriptText += fmt.Sprintf("export %s=%s\n", envvar, val)
-		}
-	}
+	netCfg := j.GetNetworkConfig()
 
 	// Add the mpirun command
-	if j.MPICfg != nil && len(j.RequiredModules) == 0 {
+	if j.MPICfg != nil && j.GetSoftwareEnv().IsEmpty() {
 		scriptText += "\nMPI_DIR=" + j.MPICfg.Implem.InstallDir + "\n"
 		scriptText += "export PATH=$MPI_DIR/bin:$PATH\n"
 		scriptText += "export LD_LIBRARY_PATH=$MPI_DIR/lib:$LD_LIBRARY_PATH\n\n"
 	}
-	mpirunArgs, errMpiArgs := mpi.GetMpirunArgs(&j.MPICfg.Implem, &j.App, sysCfg, netCfg, j.MPICfg.UserMpirunArgs)
+	mapSpec := j.Mapping
+	if mapSpec == nil && j.MPICfg.Implem.ID == openmpi.ID && j.NNodes > 0 {
+		// Historical default layout for Open MPI: ranks evenly distributed and bound to cores
+		mapSpec = mapping.ForLayout(j.NP, j.NNodes)
+	}
+	// CustomEnv is exported to the remote ranks, along with the parameters set by the user
+	userParams := j.GetEnvParams()
+	err = userParams.Merge(j.MPICfg.Params)
+	if err != nil {
+		return fmt.Errorf("invalid MPI parameters: %s", err)
+	}
+	var mpirunArgs []string
+	var errMpiArgs error
+	if j.MPICfg.ParamsAsEnv {
+		var mpiParams *params.Set
+		mpirunArgs, mpiParams, errMpiArgs = mpi.GetMpirunArgsAndParams(&j.MPICfg.Implem, &j.App, sysCfg, netCfg, mapSpec, userParams, j.MPICfg.UserMpirunArgs)
+		if errMpiArgs == nil {
+			scriptText += mpiParams.Exports()
+			mpirunArgs = append(mpirunArgs, mpi.GetEnvForwardArgs(&j.MPICfg.Implem, mpiParams)...)
+		}
+	} else {
+		mpirunArgs, errMpiArgs = mpi.GetMpirunArgs(&j.MPICfg.Implem, &j.App, sysCfg, netCfg, mapSpec, userParams, j.MPICfg.UserMpirunArgs)
+	}
 	if errMpiArgs != nil {
-		return fmt.Errorf("unable to get mpirun arguments: %s", err)
+		return fmt.Errorf("unable to get mpirun arguments: %s", errMpiArgs)
 	}
 
+	scriptText += getRankOutputDirSetup(j)
 	scriptText += "\nwhich mpirun\n"
 
 	scriptText += "\nmpirun "
 	if j.NP > 0 {
 		scriptText += fmt.Sprintf("-np %d ", j.NP)
 	}
-	// todo: this should really be in the openmpi package
-	if j.MPICfg.Implem.ID == openmpi.ID {
-		ppr := j.NP / j.NNodes
-		scriptText += fmt.Sprintf("--map-by ppr:%d:node -rank-by core -bind-to core", ppr)
+	mpirunArgs = append(mpirunArgs, mpi.GetLauncherArgs(&j.MPICfg.Implem, mpi.ResourceManagerSlurm)...)
+	rankOutputArgs, err := getMpirunRankOutputArgs(j)
+	if err != nil {
+		return err
 	}
-	scriptText += " " + strings.Join(mpirunArgs, " ") + " " + j.App.BinPath
-	if len(j.App.BinArgs) > 0 {
-		scriptText += " " + strings.Join(j.App.BinArgs, " ")
+	mpirunArgs = append(mpirunArgs, rankOutputArgs...)
+	appArgs, err := j.GetAppArgs()
+	if err != nil {
+		return fmt.Errorf("unable to get the application command: %s", err)
 	}
-	scriptText += "\n"
+	scriptText += strings.Join(mpirunArgs, " ") + " " + strings.Join(appArgs, " ") + "\n"
 
 	err = ioutil.WriteFile(j.BatchScript, []byte(scriptText), 0644)
 	if err != nil {
@@ -243,7 +311,15 @@ func setupNonMpiJob(j *job.Job, sysCfg *sys.Config) error {
 	if err != nil {
 		return err
 	}
-	scriptText += "\n" + j.App.BinPath + "\n"
+	appArgs, err := j.GetAppArgs()
+	if err != nil {
+		return fmt.Errorf("unable to get the application command: %s", err)
+	}
+	if j.PerRankOutput {
+		scriptText += getRankOutputDirSetup(j)
+		appArgs = append(getSrunArgs(j), appArgs...)
+	}
+	scriptText += "\n" + strings.Join(appArgs, " ") + "\n"
 
 	err = os.WriteFile(j.BatchScript, []byte(scriptText), 0644)
 	if err != nil {
@@ -290,34 +366,56 @@ func generateJobScript(j *job.Job, sysCfg *sys.Config) error {
 	return nil
 }
 
+// slurmPostJob gathers the output of a job once completed. The error of the submission, if any, is preserved
+// so the failure of the job can be classified.
 func slurmPostJob(cmdRes *advexec.Result, j *job.Job, sysCfg *sys.Config) advexec.Result {
 	var expRes advexec.Result
-	expRes.Err = cmdRes.Err
+	var readErrs []string
 
 	stdoutFile := getJobOutputFilePath(j, sysCfg)
-	if j.RunDir != "" {
-		stdoutFile = filepath.Join(j.RunDir, stdoutFile)
-	}
-	outputFileContent, err := ioutil.ReadFile(stdoutFile)
+	outputFileContent, err := os.ReadFile(stdoutFile)
 	if err != nil {
-		expRes.Err = fmt.Errorf("unable to read %s: %s", stdoutFile, err)
-		return expRes
+		readErrs = append(readErrs, fmt.Sprintf("unable to read %s: %s", stdoutFile, err))
 	}
 	expRes.Stdout = string(outputFileContent)
 
-	stderrFile := getJobOutputFilePath(j, sysCfg)
-	if j.RunDir != "" {
-		stderrFile = filepath.Join(j.RunDir, stderrFile)
-	}
-	errFileContent, err := ioutil.ReadFile(stderrFile)
+	stderrFile := getJobErrorFilePath(j, sysCfg)
+	errFileContent, err := os.ReadFile(stderrFile)
 	if err != nil {
-		expRes.Err = fmt.Errorf("unable to read %s: %s", stderrFile, err)
-		return expRes
+		readErrs = append(readErrs, fmt.Sprintf("unable to read %s: %s", stderrFile, err))
 	}
 	expRes.Stderr = string(errFileContent)
+
+	switch {
+	case cmdRes.Err != nil && len(readErrs) > 0:
+		expRes.Err = fmt.Errorf("%w (%s)", cmdRes.Err, strings.Join(readErrs, ", "))
+	case cmdRes.Err != nil:
+		expRes.Err = cmdRes.Err
+	case len(readErrs) > 0:
+		expRes.Err = fmt.Errorf("%s", strings.Join(readErrs, ", "))
+	}
 	return expRes
 }
 
+// getSbatchArgs returns the arguments of sbatch to submit a job: the arguments of the job manager, -W for
+// blocking jobs (the default) so sbatch returns once the job completed, and the batch script
+func getSbatchArgs(jobmgr *JM, j *job.Job) []string {
+	args := append([]string(nil), jobmgr.CmdArgs...)
+	if !j.NonBlocking {
+		args = append(args, "-W")
+	}
+	return append(args, j.BatchScript)
+}
+
+// getSbatchTimeout returns the maximum time sbatch can run to submit a job: blockingSubmitTimeout for blocking
+// jobs, since sbatch waits for the completion of the job, and the default timeout of commands otherwise
+func getSbatchTimeout(j *job.Job) time.Duration {
+	if j.NonBlocking {
+		return 0
+	}
+	return blockingSubmitTimeout
+}
+
 // slurmSubmit prepares the batch script necessary to start a given job.
 //
 // Note that a script does not need any specific environment to be submitted
@@ -343,16 +441,8 @@ func slurmSubmit(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result {
 
 	cmd.BinPath = jobmgr.BinPath
 	cmd.ExecDir = j.RunDir
-	// We want the default to be blocking sbatch but users can request non-blocking
-	if !j.NonBlocking {
-		jobmgr.CmdArgs = append(jobmgr.CmdArgs, "-W")
-	}
-
-	if len(jobmgr.CmdArgs) > 0 {
-		cmd.CmdArgs = append(cmd.CmdArgs, jobmgr.CmdArgs...)
-	}
-	//cmd.CmdArgs = append(cmd.CmdArgs, j.BatchScript)
-	cmd.CmdArgs = []string{j.BatchScript}
+	cmd.CmdArgs = getSbatchArgs(jobmgr, j)
+	cmd.Timeout = getSbatchTimeout(j)
 
 	j.SetOutputFn(slurmGetOutput)
 	j.SetErrorFn(slurmGetError)
@@ -362,6 +452,12 @@ func slurmSubmit(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result {
 		return resExec
 	}
 
+	// Let other processes reattach to the job, even if we do not get its ID
+	err = jobmgr.writeJobMetadata(j, sysCfg)
+	if err != nil {
+		log.Printf("[WARN] %s", err)
+	}
+
 	cmdRes := cmd.Run()
 	if strings.HasPrefix(cmdRes.Stdout, slurmJobIDPrefix) {
 		jobIDStr := strings.TrimPrefix(cmdRes.Stdout, slurmJobIDPrefix)
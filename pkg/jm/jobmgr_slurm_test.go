@@ -13,13 +13,19 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gvallee/go_exec/pkg/advexec"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/container"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/mpi"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/softenv"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
+	"github.com/gvallee/go_hpcjob/pkg/hpcjob"
 	"github.com/gvallee/go_util/pkg/util"
 )
 
@@ -154,3 +160,465 @@ func TestSlurmSubmitMPI(t *testing.T) {
 
 	runAndCheckJob(t, jobmgr, j, sysCfg)
 }
+
+func TestSetupMpiJobMapping(t *testing.T) {
+	dir := t.TempDir()
+	var j job.Job
+	j.Name = "mapping"
+	j.App.BinPath = "/bin/true"
+	j.BatchScript = filepath.Join(dir, "job.sh")
+	j.NP = 4
+	j.MPICfg = new(mpi.Config)
+	j.MPICfg.Implem = implem.Info{ID: implem.OMPI, Version: "4.1.5", InstallDir: "/opt/openmpi"}
+	sysCfg := sys.Config{ScratchDir: dir}
+
+	// The number of nodes is unknown, no default layout must be used
+	err := setupMpiJob(&j, &sysCfg)
+	if err != nil {
+		t.Fatalf("setupMpiJob() failed: %s", err)
+	}
+	content, err := os.ReadFile(j.BatchScript)
+	if err != nil {
+		t.Fatalf("unable to read %s: %s", j.BatchScript, err)
+	}
+	if !strings.Contains(string(content), "mpirun -np 4 ") || strings.Contains(string(content), "ppr:") {
+		t.Fatalf("invalid batch script:\n%s", content)
+	}
+
+	j.NNodes = 2
+	err = setupMpiJob(&j, &sysCfg)
+	if err != nil {
+		t.Fatalf("setupMpiJob() failed: %s", err)
+	}
+	content, err = os.ReadFile(j.BatchScript)
+	if err != nil {
+		t.Fatalf("unable to read %s: %s", j.BatchScript, err)
+	}
+	if !strings.Contains(string(content), "--map-by ppr:2:node --rank-by core --bind-to core") {
+		t.Fatalf("invalid batch script:\n%s", content)
+	}
+}
+
+func TestSetupMpiJobParamsAsEnv(t *testing.T) {
+	dir := t.TempDir()
+	var j job.Job
+	j.Name = "params"
+	j.App.BinPath = "/bin/true"
+	j.BatchScript = filepath.Join(dir, "job.sh")
+	j.NP = 2
+	j.MPICfg = new(mpi.Config)
+	j.MPICfg.Implem = implem.Info{ID: implem.OMPI, Version: "4.1.5", InstallDir: "/opt/openmpi"}
+	j.MPICfg.UserMpirunArgs = []string{"--mca", "pml", "ob1"}
+	j.MPICfg.ParamsAsEnv = true
+	j.CustomEnv = map[string]string{"FOO": "1"}
+	sysCfg := sys.Config{ScratchDir: dir}
+
+	err := setupMpiJob(&j, &sysCfg)
+	if err != nil {
+		t.Fatalf("setupMpiJob() failed: %s", err)
+	}
+	content, err := os.ReadFile(j.BatchScript)
+	if err != nil {
+		t.Fatalf("unable to read %s: %s", j.BatchScript, err)
+	}
+	if !strings.Contains(string(content), "export OMPI_MCA_pml=ob1\n") || strings.Contains(string(content), "--mca") {
+		t.Fatalf("invalid batch script:\n%s", content)
+	}
+	if !strings.Contains(string(content), "export FOO=1\n") || !strings.Contains(string(content), " -x FOO ") {
+		t.Fatalf("CustomEnv is not exported to the ranks:\n%s", content)
+	}
+}
+
+func TestSetupMpiJobCustomEnv(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		implemID string
+		expected string
+	}{
+		{implem.OMPI, " -x FOO=1 "},
+		{implem.MPICH, " -genv FOO=1 "},
+	}
+	for _, tt := range tests {
+		var j job.Job
+		j.Name = "env"
+		j.App.BinPath = "/bin/true"
+		j.BatchScript = filepath.Join(dir, "job.sh")
+		j.NP = 2
+		j.CustomEnv = map[string]string{"FOO": "1"}
+		j.MPICfg = &mpi.Config{Implem: implem.Info{ID: tt.implemID, InstallDir: "/opt/mpi"}}
+		sysCfg := sys.Config{ScratchDir: dir}
+
+		err := setupMpiJob(&j, &sysCfg)
+		if err != nil {
+			t.Fatalf("setupMpiJob() failed: %s", err)
+		}
+		content, err := os.ReadFile(j.BatchScript)
+		if err != nil {
+			t.Fatalf("unable to read %s: %s", j.BatchScript, err)
+		}
+		// CustomEnv is sent to the ranks by mpirun
+		mpirunLine := ""
+		for _, line := range strings.Split(string(content), "\n") {
+			if strings.HasPrefix(line, "mpirun ") {
+				mpirunLine = line
+			}
+		}
+		if !strings.Contains(mpirunLine, tt.expected) {
+			t.Fatalf("CustomEnv is not exported to the ranks with %s: %q", tt.implemID, mpirunLine)
+		}
+	}
+}
+
+func TestSetupMpiJobSoftwareEnv(t *testing.T) {
+	dir := t.TempDir()
+	var j job.Job
+	j.Name = "softenv"
+	j.App.BinPath = "/bin/true"
+	j.BatchScript = filepath.Join(dir, "job.sh")
+	j.MPICfg = new(mpi.Config)
+	j.MPICfg.Implem = implem.Info{ID: implem.MPICH, Version: "4.1", InstallDir: "/opt/mpich"}
+	j.RequiredModules = []string{"mpich/4.1"}
+	j.SoftwareEnv = &softenv.Env{Steps: []softenv.Step{{Kind: softenv.KindSpackLoad, Args: []string{"hdf5"}}}}
+	sysCfg := sys.Config{ScratchDir: dir}
+
+	err := setupMpiJob(&j, &sysCfg)
+	if err != nil {
+		t.Fatalf("setupMpiJob() failed: %s", err)
+	}
+	content, err := os.ReadFile(j.BatchScript)
+	if err != nil {
+		t.Fatalf("unable to read %s: %s", j.BatchScript, err)
+	}
+	expected := "module purge\nmodule load mpich/4.1\neval \"$(spack load --sh hdf5)\"\n"
+	if !strings.Contains(string(content), expected) || strings.Contains(string(content), "MPI_DIR=") {
+		t.Fatalf("invalid batch script:\n%s", content)
+	}
+}
+
+func TestSetupMpiJobContainer(t *testing.T) {
+	dir := t.TempDir()
+	var j job.Job
+	j.Name = "container"
+	j.App.BinPath = "/opt/app/bin/app"
+	j.BatchScript = filepath.Join(dir, "job.sh")
+	j.NP = 2
+	j.MPICfg = new(mpi.Config)
+	j.MPICfg.Implem = implem.Info{ID: implem.MPICH, Version: "4.1", InstallDir: "/opt/mpich"}
+	j.Container = &container.Spec{Image: "/images/app.sif", Options: []string{"--nv"}, MPIID: implem.MPICH, MPIVersion: ">=4.1"}
+	sysCfg := sys.Config{ScratchDir: dir}
+
+	err := setupMpiJob(&j, &sysCfg)
+	if err != nil {
+		t.Fatalf("setupMpiJob() failed: %s", err)
+	}
+	content, err := os.ReadFile(j.BatchScript)
+	if err != nil {
+		t.Fatalf("unable to read %s: %s", j.BatchScript, err)
+	}
+	if !strings.Contains(string(content), " apptainer exec --nv /images/app.sif /opt/app/bin/app\n") {
+		t.Fatalf("invalid batch script:\n%s", content)
+	}
+
+	j.MPICfg.Implem.Version = "3.4"
+	err = setupMpiJob(&j, &sysCfg)
+	if err == nil {
+		t.Fatalf("setupMpiJob() succeeded with an incompatible host MPI")
+	}
+}
+
+func TestParseSacctOutput(t *testing.T) {
+	output := "4242|CANCELLED by 1000|0:15|2025-03-01T10:00:00|2025-03-01T11:02:03|3723|node[01-02]||1-02:03:04\n" +
+		"4242.batch|CANCELLED|0:15|2025-03-01T10:00:00|2025-03-01T11:02:03|3723|node01|2048K|00:01.500\n" +
+		"4242.0|CANCELLED|0:15|2025-03-01T10:00:01|2025-03-01T11:02:03|3722|node[01-02]|1.5G|1-02:03:02\n"
+	a, err := parseSacctOutput(output)
+	if err != nil {
+		t.Fatalf("parseSacctOutput() failed: %s", err)
+	}
+	if a.JobID != 4242 || a.State != "CANCELLED" || a.ExitCode != 0 || a.Signal != 15 {
+		t.Fatalf("invalid accounting record: %+v", a)
+	}
+	if a.Elapsed != 3723*time.Second || a.End.Sub(a.Start) != a.Elapsed {
+		t.Fatalf("invalid times: %+v", a)
+	}
+	if strings.Join(a.Nodes, ",") != "node01,node02" {
+		t.Fatalf("invalid list of nodes: %v", a.Nodes)
+	}
+	if a.MaxRSS != 1536*1024*1024 {
+		t.Fatalf("invalid MaxRSS: %d", a.MaxRSS)
+	}
+	if a.CPUTime != 26*time.Hour+3*time.Minute+4*time.Second {
+		t.Fatalf("invalid CPU time: %s", a.CPUTime)
+	}
+
+	_, err = parseSacctOutput("")
+	if err == nil {
+		t.Fatalf("parseSacctOutput() succeeded without data")
+	}
+}
+
+func TestRecordFromScontrol(t *testing.T) {
+	output := `JobId=1234 JobName=ring
+   UserId=user(1000) GroupId=user(1000) MCS_label=N/A
+   JobState=RUNNING Reason=None Dependency=(null)
+   Partition=debug AllocNode:Sid=login1:4242
+   NumNodes=2-4 NumCPUs=8 NumTasks=8 CPUs/Task=1 ReqB:S:C:T=0:0:*:*
+   Command=/scratch/sbatch-ring.sh
+   WorkDir=/scratch
+   StdErr=/scratch/ring.err
+   StdIn=/dev/null
+   StdOut=/scratch/ring.out
+`
+	r, err := recordFromScontrol(output)
+	if err != nil {
+		t.Fatalf("recordFromScontrol() failed: %s", err)
+	}
+	if r.JobID != 1234 || r.Name != "ring" || r.State != "RUNNING" || r.BatchScript != "/scratch/sbatch-ring.sh" ||
+		r.OutputFile != "/scratch/ring.out" || r.ErrorFile != "/scratch/ring.err" || r.Spec.NNodes != 2 || r.Spec.NP != 8 {
+		t.Fatalf("invalid record: %+v", r)
+	}
+
+	_, err = recordFromScontrol("slurm_load_jobs error: Invalid job id specified")
+	if err == nil {
+		t.Fatalf("recordFromScontrol() succeeded with an invalid output")
+	}
+}
+
+func TestReattach(t *testing.T) {
+	runDir := t.TempDir()
+	sysCfg := sys.Config{JobStoreDir: t.TempDir()}
+	jobmgr := JM{ID: SlurmID, reattachJM: slurmReattach}
+	jobmgr.submitJM = func(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result {
+		j.SetTimestamp()
+		j.BatchScript = filepath.Join(j.RunDir, "sbatch-test.sh")
+		err := jobmgr.writeJobMetadata(j, sysCfg)
+		if err != nil {
+			return advexec.Result{Err: err}
+		}
+		j.ID = 42
+		return advexec.Result{}
+	}
+	j := job.Job{
+		Name:        "test",
+		RunDir:      runDir,
+		NonBlocking: true,
+		MPICfg:      &mpi.Config{Implem: implem.Info{ID: implem.OMPI, Version: "4.1.5"}},
+	}
+	res := jobmgr.Submit(&j, &sysCfg)
+	if res.Err != nil {
+		t.Fatalf("Submit() failed: %s", res.Err)
+	}
+	defer j.CleanUp()
+	outputFile := getJobOutputFilePath(&j, &sysCfg)
+	err := os.WriteFile(outputFile, []byte("Hello"), 0644)
+	if err != nil {
+		t.Fatalf("unable to write %s: %s", outputFile, err)
+	}
+
+	// A new process knows nothing about the job but its ID
+	reattached, err := jobmgr.Reattach(42, &sysCfg)
+	if err != nil {
+		t.Fatalf("Reattach() failed: %s", err)
+	}
+	if reattached.ID != 42 || reattached.ExecutionTimestamp != j.ExecutionTimestamp || reattached.MPICfg == nil || reattached.MPICfg.Implem.Version != "4.1.5" {
+		t.Fatalf("invalid reattached job: %+v", reattached)
+	}
+	if reattached.GetOutput(&sysCfg) != "Hello" {
+		t.Fatalf("unable to get the output of the reattached job: %q", reattached.GetOutput(&sysCfg))
+	}
+	postRes := slurmPostJob(&advexec.Result{}, reattached, &sysCfg)
+	if postRes.Stdout != "Hello" {
+		t.Fatalf("unable to get the output of the reattached job after completion: %+v", postRes)
+	}
+
+	_, err = jobmgr.Reattach(43, &sysCfg)
+	if err == nil {
+		t.Fatalf("Reattach() succeeded with an unknown job")
+	}
+}
+
+func TestReattachArchive(t *testing.T) {
+	runDir := t.TempDir()
+	sysCfg := sys.Config{JobStoreDir: t.TempDir()}
+	jobmgr := JM{ID: SlurmID, reattachJM: slurmReattach}
+	jobmgr.submitJM = func(j *job.Job, jobmgr *JM, sysCfg *sys.Config) advexec.Result {
+		j.SetTimestamp()
+		j.ID = 42
+		return advexec.Result{}
+	}
+	jobmgr.jobStatusJM = func(jobmgr *JM, jobIDs []int) ([]hpcjob.Status, error) {
+		return []hpcjob.Status{hpcjob.StatusDone}, nil
+	}
+	jobmgr.accountingJM = func(jobmgr *JM, j *job.Job) (*job.Accounting, error) {
+		return &job.Accounting{JobID: j.ID, State: job.StateCompleted}, nil
+	}
+	j := job.Job{
+		Name:        "test",
+		RunDir:      runDir,
+		NonBlocking: true,
+		OutputDir:   "out",
+		Artifacts:   []string{"result.txt"},
+		Archive:     &job.ArchiveSpec{Dir: t.TempDir()},
+	}
+	res := jobmgr.Submit(&j, &sysCfg)
+	if res.Err != nil {
+		t.Fatalf("Submit() failed: %s", res.Err)
+	}
+	err := os.WriteFile(filepath.Join(runDir, "result.txt"), []byte("42\n"), 0644)
+	if err != nil {
+		t.Fatalf("unable to write the artifact: %s", err)
+	}
+
+	// A new process archives the job once completed, as requested when the job was submitted
+	reattached, err := jobmgr.Reattach(42, &sysCfg)
+	if err != nil {
+		t.Fatalf("Reattach() failed: %s", err)
+	}
+	err = jobmgr.Wait(reattached, &sysCfg, time.Millisecond)
+	if err != nil {
+		t.Fatalf("Wait() failed: %s", err)
+	}
+	if !util.FileExists(filepath.Join(runDir, "out", "result.txt")) {
+		t.Fatalf("the artifact of the reattached job is not collected")
+	}
+	archives, err := filepath.Glob(filepath.Join(j.Archive.Dir, "*.tar.gz"))
+	if err != nil || len(archives) != 1 {
+		t.Fatalf("the reattached job is not archived: %v (%v)", archives, err)
+	}
+	files := readArchive(t, archives[0])
+	found := false
+	for name, content := range files {
+		found = found || (strings.HasSuffix(name, "/artifacts/result.txt") && content == "42\n")
+	}
+	if !found {
+		t.Fatalf("the artifact is not in the archive: %v", files)
+	}
+}
+
+func TestRankOutput(t *testing.T) {
+	dir := t.TempDir()
+	var j job.Job
+	j.Name = "ranks"
+	j.App.BinPath = "/bin/hostname"
+	j.BatchScript = filepath.Join(dir, "job.sh")
+	j.RunDir = dir
+	j.NP = 2
+	j.PerRankOutput = true
+	j.RankOutputDir = "ranks"
+	sysCfg := sys.Config{ScratchDir: dir}
+
+	err := setupNonMpiJob(&j, &sysCfg)
+	if err != nil {
+		t.Fatalf("setupNonMpiJob() failed: %s", err)
+	}
+	content, err := os.ReadFile(j.BatchScript)
+	if err != nil {
+		t.Fatalf("unable to read %s: %s", j.BatchScript, err)
+	}
+	rankDir := filepath.Join(dir, "ranks")
+	expected := "srun -n 2 --output=" + rankDir + "/rank.%t.out --error=" + rankDir + "/rank.%t.err /bin/hostname\n"
+	if !strings.Contains(string(content), "mkdir -p "+rankDir+"\n") || !strings.Contains(string(content), expected) {
+		t.Fatalf("invalid batch script:\n%s", content)
+	}
+
+	// Output written by srun to the files of each task
+	err = os.MkdirAll(rankDir, 0755)
+	if err != nil {
+		t.Fatalf("unable to create %s: %s", rankDir, err)
+	}
+	for rank, output := range []string{"node01\n", "node02\n"} {
+		err = os.WriteFile(filepath.Join(rankDir, "rank."+strconv.Itoa(rank)+".out"), []byte(output), 0644)
+		if err != nil {
+			t.Fatalf("unable to write the output of rank %d: %s", rank, err)
+		}
+	}
+	jobmgr := JM{ID: SlurmID}
+	out, err := jobmgr.GetRankOutput(&j, &sysCfg)
+	if err != nil {
+		t.Fatalf("GetRankOutput() failed: %s", err)
+	}
+	if len(out.Stdout) != 2 || out.Stdout[0] != "node01\n" || out.Stdout[1] != "node02\n" || len(out.Stderr) != 0 {
+		t.Fatalf("GetRankOutput() returned %+v", out)
+	}
+
+	// Output labeled by srun
+	j.RankOutputDir = ""
+	j.SetOutputFn(func(*job.Job, *sys.Config) string { return " 1: node02\n 0: node01\n" })
+	j.SetErrorFn(func(*job.Job, *sys.Config) string { return "" })
+	out, err = jobmgr.GetRankOutput(&j, &sysCfg)
+	if err != nil {
+		t.Fatalf("GetRankOutput() failed: %s", err)
+	}
+	if len(out.Stdout) != 2 || out.Stdout[0] != "node01\n" || out.Stdout[1] != "node02\n" {
+		t.Fatalf("GetRankOutput() returned %+v", out)
+	}
+
+	// MPI job with the output tagged by mpirun
+	j.MPICfg = &mpi.Config{Implem: implem.Info{ID: implem.MPICH, Version: "4.1", InstallDir: "/opt/mpich"}}
+	err = setupMpiJob(&j, &sysCfg)
+	if err != nil {
+		t.Fatalf("setupMpiJob() failed: %s", err)
+	}
+	content, err = os.ReadFile(j.BatchScript)
+	if err != nil {
+		t.Fatalf("unable to read %s: %s", j.BatchScript, err)
+	}
+	if !strings.Contains(string(content), "-prepend-rank") {
+		t.Fatalf("invalid batch script:\n%s", content)
+	}
+
+	// The directory of the files of each rank is quoted
+	j.RankOutputDir = "my ranks"
+	setup := getRankOutputDirSetup(&j)
+	if setup != "\nmkdir -p '"+filepath.Join(dir, "my ranks")+"'\n" {
+		t.Fatalf("getRankOutputDirSetup() returned %q", setup)
+	}
+}
+
+func TestGenerateBatchScriptContentCustomEnv(t *testing.T) {
+	dir := t.TempDir()
+	var j job.Job
+	j.Name = "env"
+	j.BatchScript = filepath.Join(dir, "job.sh")
+	j.RunDir = dir
+	j.CustomEnv = map[string]string{"PLAIN": "1", "SPACES": "a b", "SHELL_CODE": "$(rm -rf /); echo 'x'"}
+	sysCfg := sys.Config{ScratchDir: dir}
+
+	content, err := generateBatchScriptContent(&j, &sysCfg)
+	if err != nil {
+		t.Fatalf("generateBatchScriptContent() failed: %s", err)
+	}
+	for _, expected := range []string{"export PLAIN=1\n", "export SPACES='a b'\n", `export SHELL_CODE='$(rm -rf /); echo '\''x'\'''` + "\n"} {
+		if !strings.Contains(content, expected) {
+			t.Fatalf("%q not in the batch script:\n%s", expected, content)
+		}
+	}
+}
+
+func TestGetSbatchArgs(t *testing.T) {
+	jobmgr := JM{ID: SlurmID, CmdArgs: []string{"--qos=debug"}}
+	j := job.Job{BatchScript: "/tmp/job.sh"}
+
+	// Blocking jobs wait for completion; the arguments do not accumulate from one submission to another
+	for i := 0; i < 2; i++ {
+		args := getSbatchArgs(&jobmgr, &j)
+		if strings.Join(args, " ") != "--qos=debug -W /tmp/job.sh" {
+			t.Fatalf("getSbatchArgs() returned %v", args)
+		}
+	}
+	if len(jobmgr.CmdArgs) != 1 {
+		t.Fatalf("the arguments of the job manager were modified: %v", jobmgr.CmdArgs)
+	}
+	// sbatch waits for the job, which may stay in the queue and run longer than the default timeout of commands
+	if getSbatchTimeout(&j) < 24*time.Hour {
+		t.Fatalf("the timeout of blocking submissions is too short: %s", getSbatchTimeout(&j))
+	}
+
+	j.NonBlocking = true
+	args := getSbatchArgs(&jobmgr, &j)
+	if strings.Join(args, " ") != "--qos=debug /tmp/job.sh" {
+		t.Fatalf("getSbatchArgs() returned %v", args)
+	}
+	if getSbatchTimeout(&j) != 0 {
+		t.Fatalf("non-blocking submissions do not use the default timeout: %s", getSbatchTimeout(&j))
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package jm
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
+)
+
+const (
+	// OutputPatternName is replaced by the name of the job in Job.OutputDir and Job.OutputPattern
+	OutputPatternName = "{name}"
+
+	// OutputPatternID is replaced by the ID of the job in Job.OutputPattern
+	OutputPatternID = "{id}"
+
+	// OutputPatternTimestamp is replaced by the execution timestamp of the job in Job.OutputDir and Job.OutputPattern
+	OutputPatternTimestamp = "{timestamp}"
+
+	// OutputPatternMPI is replaced by the MPI implementation and version used by the job, if any, in
+	// Job.OutputDir and Job.OutputPattern
+	OutputPatternMPI = "{mpi}"
+
+	// OutputPatternAttempt is replaced by the number of the attempt to run the job in Job.OutputDir and Job.OutputPattern
+	OutputPatternAttempt = "{attempt}"
+
+	// slurmJobIDPattern is replaced by Slurm with the ID of the job in the names of output files
+	slurmJobIDPattern = "%j"
+)
+
+// expandOutputPattern replaces the placeholders of an output pattern with the values of a job. The ID of
+// the job is replaced by jobID, e.g., a pattern of the job manager when the job has not been submitted yet.
+func expandOutputPattern(pattern string, j *job.Job, jobID string) string {
+	mpiStr := ""
+	if j.MPICfg != nil && j.MPICfg.Implem.ID != "" {
+		mpiStr = j.MPICfg.Implem.ID + j.MPICfg.Implem.Version
+	}
+	attempt := j.Attempt
+	if attempt < 1 {
+		attempt = 1
+	}
+	r := strings.NewReplacer(
+		OutputPatternName, j.Name,
+		OutputPatternID, jobID,
+		OutputPatternTimestamp, j.ExecutionTimestamp,
+		OutputPatternMPI, mpiStr,
+		OutputPatternAttempt, strconv.Itoa(attempt))
+	return r.Replace(pattern)
+}
+
+// expandSlurmJobID replaces the Slurm pattern of the job ID in the path to an output file, once the ID is known
+func expandSlurmJobID(path string, j *job.Job) string {
+	if j.ID == 0 {
+		return path
+	}
+	return strings.ReplaceAll(path, slurmJobIDPattern, strconv.Itoa(j.ID))
+}
+
+// getJobOutputDir returns the absolute path to the directory where the output files of a job are written and
+// its artifacts collected: Job.OutputDir if set, a directory named after the job in sysCfg.OutputDir if set,
+// and the run directory of the job otherwise
+func getJobOutputDir(j *job.Job, sysCfg *sys.Config) string {
+	dir := j.RunDir
+	switch {
+	case j.OutputDir != "":
+		dir = expandOutputPattern(j.OutputDir, j, "")
+		if !filepath.IsAbs(dir) && j.RunDir != "" {
+			dir = filepath.Join(j.RunDir, dir)
+		}
+	case sysCfg != nil && sysCfg.OutputDir != "":
+		dir = filepath.Join(sysCfg.OutputDir, getJobOutFilenamePrefix(j))
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	return absDir
+}
+
+// getJobOutputName returns the name of the output files of a job, without extension. The ID of a job that
+// has not been submitted yet is the pattern replaced by Slurm.
+func getJobOutputName(j *job.Job) string {
+	if j.OutputPattern == "" {
+		return getJobOutFilenamePrefix(j)
+	}
+	jobID := slurmJobIDPattern
+	if j.ID != 0 {
+		jobID = strconv.Itoa(j.ID)
+	}
+	return expandOutputPattern(j.OutputPattern, j, jobID)
+}
+
+// prepareOutputDir checks the output policy of a job and creates its output directory
+func prepareOutputDir(j *job.Job, sysCfg *sys.Config) error {
+	if strings.Contains(j.OutputDir, OutputPatternID) {
+		return fmt.Errorf("invalid output directory %s: the job ID is not known before the directory is needed", j.OutputDir)
+	}
+	dir := getJobOutputDir(j, sysCfg)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create the output directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// copyFile copies a regular file, creating the parent directories of the destination
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	err = os.MkdirAll(filepath.Dir(dst), 0755)
+	if err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// collectArtifacts collects the artifacts of a completed job, if any. Failing to collect the artifacts
+// does not make the job fail.
+func (jobmgr *JM) collectArtifacts(j *job.Job, sysCfg *sys.Config) {
+	if len(j.Artifacts) == 0 {
+		return
+	}
+	_, err := jobmgr.CollectArtifacts(j, sysCfg)
+	if err != nil {
+		log.Printf("[WARN] unable to collect the artifacts of job %s: %s", j.Name, err)
+	}
+}
+
+// CollectArtifacts copies the artifacts declared by a job, i.e., the files matching Job.Artifacts, to its output
+// directory and returns the paths to the copies. Artifacts within the run directory keep their relative path;
+// other artifacts are copied at the top of the output directory. Directories are copied recursively.
+// Patterns matching no file are reported in the error, after the other artifacts are collected.
+func (jobmgr *JM) CollectArtifacts(j *job.Job, sysCfg *sys.Config) ([]string, error) {
+	outputDir := getJobOutputDir(j, sysCfg)
+	runDir, err := filepath.Abs(j.RunDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid run directory %s: %w", j.RunDir, err)
+	}
+
+	var collected []string
+	var missing []string
+	for _, pattern := range j.Artifacts {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(runDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return collected, fmt.Errorf("invalid artifact pattern %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			missing = append(missing, pattern)
+			continue
+		}
+		for _, match := range matches {
+			base := filepath.Dir(match)
+			if rel, err := filepath.Rel(runDir, match); err == nil && !strings.HasPrefix(rel, "..") {
+				base = runDir
+			}
+			err := filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.Type().IsRegular() {
+					return nil
+				}
+				rel, err := filepath.Rel(base, path)
+				if err != nil {
+					return err
+				}
+				dst := filepath.Join(outputDir, rel)
+				if dst != path {
+					err = copyFile(path, dst)
+					if err != nil {
+						return err
+					}
+				}
+				collected = append(collected, dst)
+				return nil
+			})
+			if err != nil {
+				return collected, fmt.Errorf("unable to collect artifact %s: %w", match, err)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return collected, fmt.Errorf("no artifact matching %s", strings.Join(missing, ", "))
+	}
+	return collected, nil
+}
@@ -0,0 +1,223 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package jm
+
+import (
+	"fmt"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/gvallee/go_exec/pkg/advexec"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
+)
+
+const (
+	// EventSubmitted is the progress event of a job that is being submitted
+	EventSubmitted = "submitted"
+
+	// EventCompleted is the progress event of a job that completed successfully
+	EventCompleted = "completed"
+
+	// EventFailed is the progress event of a job that could not be submitted or that failed
+	EventFailed = "failed"
+
+	// defaultPollInterval is how often the job manager is queried when the limit of jobs is reached
+	defaultPollInterval = 30 * time.Second
+)
+
+// Progress describes the progress of the jobs of a queue when one of them is submitted or completes
+type Progress struct {
+	// Event is what just happened to Job (e.g., EventSubmitted)
+	Event string
+
+	// Job is the job the event is about
+	Job *job.Job
+
+	// Result is the structured result of the job, set upon completion
+	Result *job.Result
+
+	// Pending is the number of jobs that are not submitted yet
+	Pending int
+
+	// Running is the number of jobs of the queue that are submitted and not completed yet
+	Running int
+
+	// Completed is the number of jobs that completed, successfully or not
+	Completed int
+
+	// Total is the number of jobs in the queue
+	Total int
+}
+
+// ProgressFn is a "function pointer" called every time a job of a queue is submitted or completes
+type ProgressFn func(p *Progress)
+
+// QueueEntry is a job of a queue with its results once completed
+type QueueEntry struct {
+	// Job is the job to submit
+	Job *job.Job
+
+	// Exec is the result of the submission of the job
+	Exec advexec.Result
+
+	// Result is the structured result of the job
+	Result *job.Result
+}
+
+// Queue is a client-side submission queue: it keeps at most MaxJobs of the jobs of the user pending or
+// running per partition, as reported by the job manager (see NumJobs), and submits more jobs as others complete.
+// Jobs are submitted in the order they are added.
+type Queue struct {
+	// MaxJobs is the maximum number of jobs of the user pending or running per partition (1 if not set)
+	MaxJobs int
+
+	// User is the user the jobs are counted for (the current user if not set)
+	User string
+
+	// PollInterval is how often the job manager is queried while the limit of jobs is reached (30 seconds if not set)
+	PollInterval time.Duration
+
+	// OnProgress is called every time a job is submitted or completes (optional)
+	OnProgress ProgressFn
+
+	entries []*QueueEntry
+
+	mutex     sync.Mutex
+	running   map[string]int
+	pending   int
+	completed int
+	done      chan struct{}
+}
+
+// Add adds a job to the queue
+func (q *Queue) Add(j *job.Job) *QueueEntry {
+	e := &QueueEntry{Job: j}
+	q.entries = append(q.entries, e)
+	return e
+}
+
+// Entries returns the jobs of the queue, in the order they were added
+func (q *Queue) Entries() []*QueueEntry {
+	return q.entries
+}
+
+func (q *Queue) notify(event string, e *QueueEntry) {
+	if q.OnProgress == nil {
+		return
+	}
+	p := &Progress{
+		Event:     event,
+		Job:       e.Job,
+		Result:    e.Result,
+		Pending:   q.pending,
+		Completed: q.completed,
+		Total:     len(q.entries),
+	}
+	for _, n := range q.running {
+		p.Running += n
+	}
+	q.OnProgress(p)
+}
+
+// numJobs returns the number of jobs of the user in a partition, which cannot be lower than the number
+// of jobs submitted from the queue that are not completed, since the job manager may not report them yet.
+// Job managers that cannot report the number of jobs, e.g., native, only rely on the jobs of the queue.
+func (q *Queue) numJobs(jobmgr *JM, partition string) int {
+	q.mutex.Lock()
+	n := q.running[partition]
+	q.mutex.Unlock()
+	if jobmgr.numJobsJM == nil {
+		return n
+	}
+	reported, err := jobmgr.NumJobs(partition, q.User)
+	if err != nil || reported < n {
+		return n
+	}
+	return reported
+}
+
+// waitForSlot waits until a job can be submitted to a partition
+func (q *Queue) waitForSlot(jobmgr *JM, partition string, maxJobs int) {
+	pollInterval := q.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	for q.numJobs(jobmgr, partition) >= maxJobs {
+		select {
+		case <-q.done:
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (q *Queue) submit(e *QueueEntry, jobmgr *JM, sysCfg *sys.Config) {
+	if e.Job.NonBlocking {
+		// Once submitted, the job is handled by the job manager and its result is pending
+		e.Exec = jobmgr.Submit(e.Job, sysCfg)
+		e.Result = jobmgr.GetResult(e.Job, &e.Exec)
+	} else {
+		e.Result, e.Exec = jobmgr.SubmitWithRetry(e.Job, sysCfg)
+	}
+
+	q.mutex.Lock()
+	q.running[e.Job.Partition]--
+	q.completed++
+	event := EventCompleted
+	if !e.Result.Success() && (!e.Job.NonBlocking || e.Result.IsFinal()) {
+		event = EventFailed
+	}
+	q.notify(event, e)
+	q.mutex.Unlock()
+
+	// Wake up the submission loop, if it is waiting
+	select {
+	case q.done <- struct{}{}:
+	default:
+	}
+}
+
+// Run submits all the jobs of the queue and returns once they all completed. Jobs are expected to be
+// blocking (the default); non-blocking jobs are considered completed once submitted but still count
+// against the limit of jobs reported by the job manager.
+func (q *Queue) Run(jobmgr *JM, sysCfg *sys.Config) error {
+	if q.User == "" {
+		u, err := user.Current()
+		if err != nil {
+			return fmt.Errorf("unable to retrieve the current user: %w", err)
+		}
+		q.User = u.Username
+	}
+	maxJobs := q.MaxJobs
+	if maxJobs <= 0 {
+		maxJobs = 1
+	}
+
+	q.running = make(map[string]int)
+	q.pending = len(q.entries)
+	q.completed = 0
+	q.done = make(chan struct{}, 1)
+
+	var wg sync.WaitGroup
+	for _, e := range q.entries {
+		q.waitForSlot(jobmgr, e.Job.Partition, maxJobs)
+
+		q.mutex.Lock()
+		q.running[e.Job.Partition]++
+		q.pending--
+		q.notify(EventSubmitted, e)
+		q.mutex.Unlock()
+
+		wg.Add(1)
+		go func(e *QueueEntry) {
+			defer wg.Done()
+			q.submit(e, jobmgr, sysCfg)
+		}(e)
+	}
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package jm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/mpich"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mpi"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/params"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
+)
+
+const (
+	// srunRankOutputPrefix is the prefix of the names of the files where srun writes the output of each task
+	srunRankOutputPrefix = "rank."
+
+	// srunRankOutputSuffix is the suffix of the names of the files where srun writes stdout of each task
+	srunRankOutputSuffix = ".out"
+
+	// srunRankErrorSuffix is the suffix of the names of the files where srun writes stderr of each task
+	srunRankErrorSuffix = ".err"
+)
+
+// srunLabelRegexp matches a line of output labeled by srun --label, e.g., "0: hello"; labels are padded with spaces
+var srunLabelRegexp = regexp.MustCompile(`^\s*(\d+): ?(.*)$`)
+
+// getMpirunRankOutputArgs returns the mpirun arguments to keep the output of each rank of a job separate
+func getMpirunRankOutputArgs(j *job.Job) ([]string, error) {
+	if !j.PerRankOutput {
+		return nil, nil
+	}
+	return mpi.GetRankOutputArgs(&j.MPICfg.Implem, j.GetRankOutputDir())
+}
+
+// getSrunArgs returns the srun command starting the tasks of a non-MPI job with the output of each task kept
+// separate, i.e., written to its own files or labeled with the task number
+func getSrunArgs(j *job.Job) []string {
+	args := []string{"srun"}
+	if j.NP > 0 {
+		args = append(args, "-n", strconv.Itoa(j.NP))
+	}
+	dir := j.GetRankOutputDir()
+	if dir == "" {
+		return append(args, "--label")
+	}
+	return append(args,
+		"--output="+filepath.Join(dir, srunRankOutputPrefix+"%t"+srunRankOutputSuffix),
+		"--error="+filepath.Join(dir, srunRankOutputPrefix+"%t"+srunRankErrorSuffix))
+}
+
+// getSrunRankOutputFiles returns the paths to the files with stdout and stderr of each task, keyed by task
+// number, written by srun in dir when using the arguments returned by getSrunArgs
+func getSrunRankOutputFiles(dir string) (map[int]string, map[int]string, error) {
+	return mpich.FindRankOutputFiles(dir, srunRankOutputPrefix, srunRankOutputSuffix, srunRankErrorSuffix)
+}
+
+// readRankFiles reads the files of each rank and returns their content keyed by rank
+func readRankFiles(files map[int]string) (map[int]string, error) {
+	content := make(map[int]string)
+	for rank, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the output of rank %d: %w", rank, err)
+		}
+		content[rank] = string(data)
+	}
+	return content, nil
+}
+
+// GetRankOutput returns the output of a completed job run with PerRankOutput, keyed by rank. The output is
+// read from the files of each rank when RankOutputDir is set, and otherwise is parsed from the output of
+// the job, where lines are tagged with the rank.
+func (jobmgr *JM) GetRankOutput(j *job.Job, sysCfg *sys.Config) (*job.RankOutput, error) {
+	if !j.PerRankOutput {
+		return nil, fmt.Errorf("job %s was not run with per-rank output", j.Name)
+	}
+	if j.MPICfg == nil && !jobmgr.isBatch() {
+		return nil, fmt.Errorf("per-rank output is not supported for non-MPI jobs with the %s job manager", jobmgr.ID)
+	}
+
+	var err error
+	out := new(job.RankOutput)
+	dir := j.GetRankOutputDir()
+	if dir == "" {
+		if j.MPICfg == nil {
+			out.Stdout = mpi.ParseTaggedLines(srunLabelRegexp, j.GetOutput(sysCfg))
+			out.Stderr = mpi.ParseTaggedLines(srunLabelRegexp, j.GetError(sysCfg))
+			return out, nil
+		}
+		out.Stdout, err = mpi.ParseTaggedOutput(&j.MPICfg.Implem, j.GetOutput(sysCfg))
+		if err != nil {
+			return nil, err
+		}
+		out.Stderr, err = mpi.ParseTaggedOutput(&j.MPICfg.Implem, j.GetError(sysCfg))
+		if err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+
+	var stdoutFiles, stderrFiles map[int]string
+	if j.MPICfg == nil {
+		stdoutFiles, stderrFiles, err = getSrunRankOutputFiles(dir)
+	} else {
+		stdoutFiles, stderrFiles, err = mpi.GetRankOutputFiles(&j.MPICfg.Implem, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out.Stdout, err = readRankFiles(stdoutFiles)
+	if err != nil {
+		return nil, err
+	}
+	out.Stderr, err = readRankFiles(stderrFiles)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// getRankOutputDirSetup returns the batch script commands creating the directory where each rank writes its output
+func getRankOutputDirSetup(j *job.Job) string {
+	dir := j.GetRankOutputDir()
+	if !j.PerRankOutput || dir == "" {
+		return ""
+	}
+	return "\nmkdir -p " + params.ShellQuote(dir) + "\n"
+}
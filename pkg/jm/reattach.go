@@ -0,0 +1,225 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package jm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gvallee/go_exec/pkg/advexec"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/jobstore"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mpi"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
+	"github.com/gvallee/go_util/pkg/util"
+)
+
+// metadataFileSuffix is the suffix of the metadata file written next to the batch script of a job
+const metadataFileSuffix = ".meta.json"
+
+// GetMetadataFilePath returns the path to the metadata file of a job that is written next to its batch script
+func GetMetadataFilePath(batchScript string) string {
+	return batchScript + metadataFileSuffix
+}
+
+// writeJobMetadata writes the metadata of a batch job next to its batch script, so another process can reattach
+// to the job. The metadata file is removed with the batch script when the job is cleaned up.
+func (jobmgr *JM) writeJobMetadata(j *job.Job, sysCfg *sys.Config) error {
+	if j.BatchScript == "" {
+		return nil
+	}
+	path := GetMetadataFilePath(j.BatchScript)
+	content, err := json.MarshalIndent(jobmgr.newStoreRecord(j, sysCfg), "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode the metadata of job %s: %w", j.Name, err)
+	}
+	created := !util.FileExists(path)
+	err = os.WriteFile(path, content, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write %s: %w", path, err)
+	}
+	if created {
+		j.AddCleanUp(func(...interface{}) error {
+			err := os.RemoveAll(path)
+			if err != nil {
+				return fmt.Errorf("unable to delete %s: %s", path, err)
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// LoadMetadata reads the metadata file written next to the batch script of a job
+func LoadMetadata(path string) (*jobstore.Record, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	r := new(jobstore.Record)
+	err = json.Unmarshal(content, r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata file %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// jobFromRecord rebuilds a job from its record, so its output can be retrieved from another process
+func jobFromRecord(r *jobstore.Record) *job.Job {
+	j := &job.Job{
+		Name:               r.Name,
+		ID:                 r.JobID,
+		App:                r.Spec.App,
+		NP:                 r.Spec.NP,
+		NNodes:             r.Spec.NNodes,
+		Partition:          r.Spec.Partition,
+		RunDir:             r.Spec.RunDir,
+		BatchScript:        r.BatchScript,
+		ExecutionTimestamp: r.ExecutionTimestamp,
+		Attempt:            r.Attempt,
+		OutputFile:         r.OutputFile,
+		ErrorFile:          r.ErrorFile,
+		StoreKey:           r.Key,
+		PerRankOutput:      r.Spec.PerRankOutput,
+		RankOutputDir:      r.Spec.RankOutputDir,
+		OutputDir:          r.Spec.OutputDir,
+		Artifacts:          r.Spec.Artifacts,
+		Archive:            r.Spec.Archive,
+		// The job is handled by the job manager
+		NonBlocking: true,
+	}
+	if r.Spec.MPIID != "" {
+		j.MPICfg = &mpi.Config{Implem: implem.Info{ID: r.Spec.MPIID, Version: r.Spec.MPIVersion, InstallDir: r.Spec.MPIDir}}
+	}
+	return j
+}
+
+// parseScontrolShowJob parses the output of 'scontrol show job' and returns the value of each field
+func parseScontrolShowJob(output string) map[string]string {
+	fields := make(map[string]string)
+	for _, token := range strings.Fields(output) {
+		idx := strings.Index(token, "=")
+		if idx <= 0 {
+			continue
+		}
+		key := token[:idx]
+		// The first occurrence wins, e.g., the job when the output includes several jobs
+		if _, ok := fields[key]; !ok {
+			fields[key] = token[idx+1:]
+		}
+	}
+	return fields
+}
+
+// recordFromScontrol creates the record of a job from the output of 'scontrol show job'
+func recordFromScontrol(output string) (*jobstore.Record, error) {
+	fields := parseScontrolShowJob(output)
+	var err error
+	r := new(jobstore.Record)
+	r.JobID, err = strconv.Atoi(fields["JobId"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid job ID %s: %w", fields["JobId"], err)
+	}
+	r.Name = fields["JobName"]
+	r.State = fields["JobState"]
+	r.BatchScript = fields["Command"]
+	r.OutputFile = fields["StdOut"]
+	r.ErrorFile = fields["StdErr"]
+	r.Spec.RunDir = fields["WorkDir"]
+	r.Spec.Partition = fields["Partition"]
+	r.Spec.NonBlocking = true
+	// Values may be ranges, e.g., "2-4"
+	r.Spec.NNodes, _ = strconv.Atoi(strings.SplitN(fields["NumNodes"], "-", 2)[0])
+	r.Spec.NP, _ = strconv.Atoi(fields["NumTasks"])
+	return r, nil
+}
+
+// findSlurmRecord finds the record of a job from the metadata file next to its batch script, which is found with
+// the job store when jobs are recorded, and otherwise with 'scontrol show job'
+func findSlurmRecord(jobmgr *JM, jobID int, sysCfg *sys.Config) (*jobstore.Record, error) {
+	if sysCfg.JobStoreDir != "" {
+		s, err := jobstore.Open(sysCfg.JobStoreDir)
+		if err == nil {
+			r, err := s.FindByJobID(jobmgr.ID, jobID)
+			if err == nil {
+				if r.BatchScript != "" {
+					metadata, err := LoadMetadata(GetMetadataFilePath(r.BatchScript))
+					if err == nil && metadata.JobID == jobID {
+						return metadata, nil
+					}
+				}
+				return r, nil
+			}
+		}
+	}
+
+	var cmd advexec.Advcmd
+	var err error
+	cmd.BinPath, err = exec.LookPath("scontrol")
+	if err != nil {
+		return nil, fmt.Errorf("scontrol not found: %w", err)
+	}
+	cmd.CmdArgs = []string{"show", "job", strconv.Itoa(jobID)}
+	res := cmd.Run()
+	if res.Err != nil {
+		return nil, fmt.Errorf("unable to get the details of job %d: %w (stderr: %s)", jobID, res.Err, res.Stderr)
+	}
+	r, err := recordFromScontrol(res.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	if r.BatchScript != "" {
+		metadata, err := LoadMetadata(GetMetadataFilePath(r.BatchScript))
+		if err == nil {
+			// The metadata file is written before the job is submitted; it may not have the ID
+			metadata.JobID = jobID
+			return metadata, nil
+		}
+	}
+	return r, nil
+}
+
+func slurmReattach(jobmgr *JM, jobID int, sysCfg *sys.Config) (*job.Job, error) {
+	r, err := findSlurmRecord(jobmgr, jobID, sysCfg)
+	if err != nil {
+		return nil, err
+	}
+	j := jobFromRecord(r)
+	j.SetOutputFn(slurmGetOutput)
+	j.SetErrorFn(slurmGetError)
+	return j, nil
+}
+
+// Reattach rebuilds a job submitted by another process, e.g., a previous run of the driver, so that its output
+// can be retrieved (GetOutput, GetError, PostRun) and its completion waited for (Wait)
+func (jobmgr *JM) Reattach(jobID int, sysCfg *sys.Config) (*job.Job, error) {
+	if jobmgr.reattachJM == nil {
+		return nil, fmt.Errorf("not implemented")
+	}
+	return jobmgr.reattachJM(jobmgr, jobID, sysCfg)
+}
+
+// Wait waits for a job handled by the job manager, e.g., a non-blocking or reattached job, to complete.
+// The status of the job is queried every pollInterval (30 seconds if not set). The result of the job is then
+// recorded in the job store, its artifacts collected in its output directory and its files archived, if requested.
+func (jobmgr *JM) Wait(j *job.Job, sysCfg *sys.Config, pollInterval time.Duration) error {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	err := jobmgr.waitForCompletion(context.Background(), j, pollInterval)
+	if err != nil {
+		return err
+	}
+	jobmgr.completeJob(j, sysCfg, jobmgr.GetResult(j, &advexec.Result{}))
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package jm
+
+import (
+	"log"
+	"time"
+
+	"github.com/gvallee/go_exec/pkg/advexec"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
+)
+
+// SubmitWithRetry submits a job and returns its structured result, submitting the job again as specified by its
+// retry policy, if any, when it fails. Each attempt has its own output files and timestamp; the result of the
+// last attempt, which is returned, records the history of all the attempts. Non-blocking jobs are not retried.
+func (jobmgr *JM) SubmitWithRetry(j *job.Job, sysCfg *sys.Config) (*job.Result, advexec.Result) {
+	if j.Retry == nil || j.NonBlocking {
+		execRes := jobmgr.Submit(j, sysCfg)
+		r := jobmgr.GetResult(j, &execRes)
+		return r, execRes
+	}
+
+	// A batch script generated for an attempt must be generated again for the next one, to use new output files
+	generatedScript := j.BatchScript == ""
+	var attempts []job.Attempt
+	for n := 1; ; n++ {
+		j.Attempt = n
+		if n > 1 {
+			j.ID = 0
+			j.ExecutionTimestamp = ""
+			if generatedScript {
+				j.BatchScript = ""
+			}
+		}
+
+		start := time.Now()
+		execRes := jobmgr.Submit(j, sysCfg)
+		r := jobmgr.GetResult(j, &execRes)
+		attemptRes := *r
+		a := job.Attempt{
+			Number:             n,
+			JobID:              j.ID,
+			ExecutionTimestamp: j.ExecutionTimestamp,
+			Start:              start,
+			End:                time.Now(),
+			Result:             &attemptRes,
+		}
+		a.OutputFile, a.ErrorFile = jobmgr.getOutputFiles(j, sysCfg)
+		attempts = append(attempts, a)
+
+		if n >= j.Retry.MaxAttempts || !j.Retry.IsRetryable(r) {
+			r.Attempts = attempts
+			return r, execRes
+		}
+		backoff := j.Retry.GetBackoff(n)
+		log.Printf("-> Attempt %d of job %s failed (%s), retrying in %s", n, j.Name, r, backoff)
+		time.Sleep(backoff)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package jm
+
+import (
+	"log"
+	"time"
+
+	"github.com/gvallee/go_exec/pkg/advexec"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/jobstore"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
+)
+
+// newStoreRecord creates the record of a job for the job store
+func (jobmgr *JM) newStoreRecord(j *job.Job, sysCfg *sys.Config) *jobstore.Record {
+	r := &jobstore.Record{
+		Key:                j.StoreKey,
+		Name:               j.Name,
+		Backend:            jobmgr.ID,
+		JobID:              j.ID,
+		Attempt:            j.Attempt,
+		ExecutionTimestamp: j.ExecutionTimestamp,
+		BatchScript:        j.BatchScript,
+		Spec: jobstore.Spec{
+			App:           j.App,
+			NP:            j.NP,
+			NNodes:        j.NNodes,
+			Partition:     j.Partition,
+			NonBlocking:   j.NonBlocking,
+			RunDir:        j.RunDir,
+			PerRankOutput: j.PerRankOutput,
+			RankOutputDir: j.RankOutputDir,
+			OutputDir:     getJobOutputDir(j, sysCfg),
+			Artifacts:     j.Artifacts,
+			Archive:       j.Archive,
+		},
+	}
+	if j.MPICfg != nil {
+		r.Spec.MPIID = j.MPICfg.Implem.ID
+		r.Spec.MPIVersion = j.MPICfg.Implem.Version
+		r.Spec.MPIDir = j.MPICfg.Implem.InstallDir
+	}
+	r.OutputFile, r.ErrorFile = jobmgr.getOutputFiles(j, sysCfg)
+	return r
+}
+
+// recordSubmission records the submission of a job in the job store, when jobs are recorded. Blocking jobs
+// have completed when submitted, non-blocking jobs are recorded as submitted.
+// Failing to record a job does not make the job fail.
+func (jobmgr *JM) recordSubmission(j *job.Job, sysCfg *sys.Config, start time.Time, execRes *advexec.Result) {
+	if sysCfg.JobStoreDir == "" {
+		return
+	}
+	s, err := jobstore.Open(sysCfg.JobStoreDir)
+	if err != nil {
+		log.Printf("[WARN] unable to record job %s: %s", j.Name, err)
+		return
+	}
+
+	r := jobmgr.newStoreRecord(j, sysCfg)
+	r.SetState(jobstore.StateSubmitted, start)
+	switch {
+	case execRes.Err == nil && j.NonBlocking:
+		// The job is now handled by the job manager
+	case execRes.Err == nil:
+		r.SetState(job.StateCompleted, time.Now())
+	case j.NonBlocking || (jobmgr.isBatch() && j.ID == 0):
+		r.SetState(jobstore.StateSubmitFailed, time.Now())
+	default:
+		r.SetState(job.StateFailed, time.Now())
+	}
+	err = s.Put(r)
+	if err != nil {
+		log.Printf("[WARN] unable to record job %s: %s", j.Name, err)
+	}
+}
+
+// recordResult updates the record of a job in the job store with its structured result, when jobs are recorded
+func (jobmgr *JM) recordResult(j *job.Job, sysCfg *sys.Config, res *job.Result) {
+	if sysCfg.JobStoreDir == "" || j.StoreKey == "" {
+		return
+	}
+	state := res.State
+	if res.Failure == job.FailureSubmission {
+		state = jobstore.StateSubmitFailed
+	}
+	if state == "" {
+		return
+	}
+	s, err := jobstore.Open(sysCfg.JobStoreDir)
+	if err != nil {
+		log.Printf("[WARN] unable to record the result of job %s: %s", j.Name, err)
+		return
+	}
+	err = s.Update(j.StoreKey, func(r *jobstore.Record) bool {
+		if state == r.State && res.ExitCode == r.ExitCode {
+			return false
+		}
+		r.SetState(state, time.Now())
+		r.ExitCode = res.ExitCode
+		return true
+	})
+	if err != nil {
+		log.Printf("[WARN] unable to record the result of job %s: %s", j.Name, err)
+	}
+}
+
+// recordArchive updates the record of a job in the job store with the path to its archive, when jobs are recorded
+func (jobmgr *JM) recordArchive(j *job.Job, sysCfg *sys.Config, archive string) {
+	if sysCfg.JobStoreDir == "" || j.StoreKey == "" {
+		return
+	}
+	s, err := jobstore.Open(sysCfg.JobStoreDir)
+	if err != nil {
+		log.Printf("[WARN] unable to record the archive of job %s: %s", j.Name, err)
+		return
+	}
+	err = s.Update(j.StoreKey, func(r *jobstore.Record) bool {
+		r.Archive = archive
+		return true
+	})
+	if err != nil {
+		log.Printf("[WARN] unable to record the archive of job %s: %s", j.Name, err)
+	}
+}
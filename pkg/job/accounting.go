@@ -0,0 +1,111 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package job
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	// StateCompleted is the accounting state of a job that completed successfully
+	StateCompleted = "COMPLETED"
+
+	// StateFailed is the accounting state of a job that completed with an error
+	StateFailed = "FAILED"
+
+	// StateTimeout is the accounting state of a job that was terminated upon reaching its time limit
+	StateTimeout = "TIMEOUT"
+
+	// StateOOM is the accounting state of a job that was terminated because it ran out of memory
+	StateOOM = "OUT_OF_MEMORY"
+
+	// StateNodeFail is the accounting state of a job that was terminated because of the failure of a node
+	StateNodeFail = "NODE_FAIL"
+
+	// StateCancelled is the accounting state of a job that was cancelled by a user or an administrator
+	StateCancelled = "CANCELLED"
+
+	// StatePreempted is the accounting state of a job that was preempted
+	StatePreempted = "PREEMPTED"
+
+	// StateBootFail is the accounting state of a job that was terminated because a node failed to boot
+	StateBootFail = "BOOT_FAIL"
+
+	// StateDeadline is the accounting state of a job that was terminated upon reaching its deadline
+	StateDeadline = "DEADLINE"
+)
+
+// Accounting is the accounting record of a job once completed
+type Accounting struct {
+	// JobID is the identifier of the job (e.g., Slurm job ID, PID)
+	JobID int
+
+	// State is the final state of the job, as reported by the job manager (e.g., COMPLETED, TIMEOUT)
+	State string
+
+	// ExitCode is the exit code of the job
+	ExitCode int
+
+	// Signal is the signal that terminated the job, 0 if none
+	Signal int
+
+	// Start is when the job started
+	Start time.Time
+
+	// End is when the job completed
+	End time.Time
+
+	// Elapsed is the wall-clock time of the job
+	Elapsed time.Duration
+
+	// Nodes is the list of nodes the job ran on
+	Nodes []string
+
+	// MaxRSS is the maximum resident set size of the job in bytes (of the largest task/process)
+	MaxRSS int64
+
+	// CPUTime is the total CPU time (user and system) consumed by the job
+	CPUTime time.Duration
+}
+
+// AccountingFromProcess creates the accounting record of a job based on the state of the process
+// that ran it, e.g., mpirun. The CPU time includes the children of the process that it waited for.
+func AccountingFromProcess(ps *os.ProcessState, start time.Time, end time.Time, nodes []string) *Accounting {
+	a := &Accounting{
+		JobID:    ps.Pid(),
+		ExitCode: ps.ExitCode(),
+		Start:    start,
+		End:      end,
+		Elapsed:  end.Sub(start),
+		Nodes:    nodes,
+		CPUTime:  ps.UserTime() + ps.SystemTime(),
+	}
+	if ws, ok := ps.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		a.Signal = int(ws.Signal())
+	}
+	if ru, ok := ps.SysUsage().(*syscall.Rusage); ok {
+		// ru_maxrss is in kilobytes
+		a.MaxRSS = int64(ru.Maxrss) * 1024
+	}
+	if ps.Success() {
+		a.State = StateCompleted
+	} else {
+		a.State = StateFailed
+	}
+	return a
+}
+
+// SetAccounting sets the accounting record of the job, for job managers that do not keep track of it
+func (j *Job) SetAccounting(a *Accounting) {
+	j.accounting = a
+}
+
+// GetAccounting returns the accounting record set with SetAccounting, if any
+func (j *Job) GetAccounting() *Accounting {
+	return j.accounting
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package job
+
+const (
+	// ArchiveGzip is the format of archives compressed with gzip (.tar.gz)
+	ArchiveGzip = "gzip"
+
+	// ArchiveZstd is the format of archives compressed with zstd (.tar.zst); the zstd command must be available
+	ArchiveZstd = "zstd"
+)
+
+// ArchiveSpec specifies how the files of a job are archived once the job completed: its batch script,
+// stdout, stderr, metadata and artifacts are gathered in a compressed tarball with a manifest
+type ArchiveSpec struct {
+	// Format is the compression format of the archive (ArchiveGzip by default)
+	Format string
+
+	// Dir is the directory where the archive is created (optional, the archives directory of
+	// sys.Config.Persistent by default)
+	Dir string
+
+	// MaxFileSize is the maximum size in bytes of a file in the archive; larger files are not archived
+	// and reported as skipped in the manifest (optional, no limit by default)
+	MaxFileSize int64
+
+	// MaxSize is the maximum size in bytes of all the files in the archive, before compression; files that
+	// do not fit are not archived and reported as skipped in the manifest (optional, no limit by default).
+	// Files are added in order: metadata, batch script, stdout, stderr and artifacts.
+	MaxSize int64
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package job
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gvallee/go_hpc_jobmgr/pkg/params"
+)
+
+const (
+	// EnvInherit specifies that the job inherits the environment of the caller (default)
+	EnvInherit = "inherit"
+
+	// EnvClear specifies that the job does not inherit the environment of the caller
+	EnvClear = "clear"
+
+	// EnvAllowlist specifies that the job only inherits the variables of EnvAllowlist from the environment of the caller
+	EnvAllowlist = "allowlist"
+
+	// defaultPath is the PATH used when the environment of the caller is not inherited
+	defaultPath = "/usr/bin:/bin"
+)
+
+// CustomEnvNames returns the names of the variables of CustomEnv, sorted
+func (j *Job) CustomEnvNames() []string {
+	var names []string
+	for name := range j.CustomEnv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetEnvParams returns CustomEnv as a set of parameters, so it can be exported to remote ranks
+// through the mechanism of the MPI implementation (e.g., -x or -genv)
+func (j *Job) GetEnvParams() *params.Set {
+	set := params.New()
+	for _, name := range j.CustomEnvNames() {
+		// Names are unique so there is no conflict
+		_ = set.SetUser(params.KindEnv, name, j.CustomEnv[name])
+	}
+	return set
+}
+
+func lookupEnv(env []string, name string) (string, bool) {
+	for _, e := range env {
+		if strings.HasPrefix(e, name+"=") {
+			return strings.TrimPrefix(e, name+"="), true
+		}
+	}
+	return "", false
+}
+
+func setEnv(env []string, name string, value string) []string {
+	for idx := range env {
+		if strings.HasPrefix(env[idx], name+"=") {
+			env[idx] = name + "=" + value
+			return env
+		}
+	}
+	return append(env, name+"="+value)
+}
+
+func prependPath(dir string, cur string) string {
+	if cur == "" {
+		return dir
+	}
+	return dir + ":" + cur
+}
+
+// GetEnv returns the environment to use to run the job, based on the environment of the caller (e.g.,
+// os.Environ()) and EnvMode: the software environment is set up if any, otherwise PATH and LD_LIBRARY_PATH
+// are set for the MPI installation, and CustomEnv is added.
+func (j *Job) GetEnv(environ []string) ([]string, error) {
+	var env []string
+	switch j.EnvMode {
+	case "", EnvInherit:
+		env = append(env, environ...)
+	case EnvClear:
+	case EnvAllowlist:
+		for _, name := range j.EnvAllowlist {
+			if value, ok := lookupEnv(environ, name); ok {
+				env = append(env, name+"="+value)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid environment mode: %s", j.EnvMode)
+	}
+
+	if _, ok := lookupEnv(env, "PATH"); !ok {
+		env = append(env, "PATH="+defaultPath)
+	}
+
+	// As in batch scripts, MPI is expected to be provided by the software environment when there is one
+	softEnv := j.GetSoftwareEnv()
+	if !softEnv.IsEmpty() {
+		var err error
+		env, err = softEnv.Apply(env)
+		if err != nil {
+			return nil, err
+		}
+	} else if j.MPICfg != nil && j.MPICfg.Implem.InstallDir != "" {
+		path, _ := lookupEnv(env, "PATH")
+		env = setEnv(env, "PATH", prependPath(filepath.Join(j.MPICfg.Implem.InstallDir, "bin"), path))
+		ldPath, _ := lookupEnv(env, "LD_LIBRARY_PATH")
+		env = setEnv(env, "LD_LIBRARY_PATH", prependPath(filepath.Join(j.MPICfg.Implem.InstallDir, "lib"), ldPath))
+	}
+
+	for _, name := range j.CustomEnvNames() {
+		env = setEnv(env, name, j.CustomEnv[name])
+	}
+	return env, nil
+}
@@ -8,9 +8,17 @@ package job
 
 import (
 	"bytes"
+	"io"
+	"path/filepath"
 
+	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/network"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/app"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/container"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mapping"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/mpi"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/nodelist"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/softenv"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
 	"github.com/gvallee/go_util/pkg/timestamp"
 )
@@ -53,6 +61,13 @@ type Job struct {
 	// ErrBuffer is a buffer with the stderr of the job
 	ErrBuffer bytes.Buffer
 
+	// Stdout receives the output of the job as it is produced, in addition to OutBuffer, with job managers running
+	// the job from the local node, e.g., native and prun (optional, see NewLineWriter to use a callback)
+	Stdout io.Writer
+
+	// Stderr receives stderr of the job as it is produced, in addition to ErrBuffer (optional)
+	Stderr io.Writer
+
 	// internalGetOutput is the function to call to gather the output of the application based on the use of a given job manager
 	internalGetOutput GetOutputFn
 
@@ -71,18 +86,90 @@ type Job struct {
 	// Device is the network device to use to run the job
 	Device string
 
+	// Network is the network transport configuration to use to run the job. If set, Device takes precedence over Network.Device.
+	Network network.Config
+
+	// Container specifies the container image in which the application is executed, with the host MPI (optional)
+	Container *container.Spec
+
+	// Mapping specifies how ranks are placed and bound (optional)
+	Mapping *mapping.Spec
+
+	// Nodes is the list of nodes to use to run the job (optional)
+	Nodes []nodelist.Node
+
+	// UseAllocation specifies that the nodes of the Slurm or PBS allocation we are running in must be used when Nodes is not set
+	UseAllocation bool
+
+	// Hostfile is the path to the hostfile generated for the job, if any
+	Hostfile string
+
 	// RunDir is the path to the directory from which the job needs to be launched
 	RunDir string
 
 	// RequiredModules is the list of modules to load to be able to run the job
 	RequiredModules []string
 
+	// SoftwareEnv is the software environment to set up to run the job (optional), after loading RequiredModules
+	SoftwareEnv *softenv.Env
+
 	NonBlocking bool
 
+	// CustomEnv is a set of environment variables to set for the job, including on remote ranks
 	CustomEnv map[string]string
 
+	// EnvMode specifies how the environment of the caller is used to run the job (EnvInherit by default)
+	EnvMode string
+
+	// EnvAllowlist is the list of variables inherited from the environment of the caller with EnvAllowlist
+	EnvAllowlist []string
+
 	ExecutionTimestamp string
 
+	// OutputFile is the path to the file where the job manager writes the output (stdout) of the job, when it is
+	// not derived from the name and timestamp of the job, e.g., for a job reattached from another process (optional)
+	OutputFile string
+
+	// ErrorFile is the path to the file where the job manager writes stderr of the job, when it is not derived
+	// from the name and timestamp of the job (optional)
+	ErrorFile string
+
+	// OutputDir is the directory where the job manager writes the output files of the job and where its artifacts
+	// are collected, relative to RunDir if not absolute. It may include the placeholders of the job manager's output
+	// patterns, except the job ID (optional, see sys.Config.OutputDir for the default).
+	OutputDir string
+
+	// OutputPattern is the pattern of the names of the output files of the job, without extension, e.g.,
+	// "{name}-{id}" (optional, the name, timestamp and MPI implementation of the job by default)
+	OutputPattern string
+
+	// Artifacts is the list of files produced by the job to collect in its output directory once completed;
+	// glob patterns, relative to RunDir if not absolute, are supported (optional)
+	Artifacts []string
+
+	// Archive specifies how the files of the job are archived once it completed (optional, not archived by default)
+	Archive *ArchiveSpec
+
+	// PerRankOutput requests the output of each rank to be kept separate, so it can be retrieved with
+	// JM.GetRankOutput (MPI jobs, and Slurm jobs through srun)
+	PerRankOutput bool
+
+	// RankOutputDir is the directory where each rank writes its output to separate files with PerRankOutput,
+	// relative to RunDir if not absolute. If not set, the output of the job is tagged with the rank instead.
+	RankOutputDir string
+
+	// Retry is the policy used to submit the job again when it fails (optional, no retry by default)
+	Retry *RetryPolicy
+
+	// StoreKey is the key of the record of the job in the job store, set upon submission when jobs are recorded
+	StoreKey string
+
+	// Attempt is the number of the current attempt to run the job, starting at 1, when a retry policy is used
+	Attempt int
+
+	// accounting is the accounting record of the job, for job managers that do not keep track of it
+	accounting *Accounting
+
 	MaxExecTime string
 }
 
@@ -106,6 +193,71 @@ func (j *Job) SetErrorFn(fn GetErrorFn) {
 	j.internalGetError = fn
 }
 
+// GetNetworkConfig returns the network configuration to use for the job
+func (j *Job) GetNetworkConfig() *network.Config {
+	netCfg := new(network.Config)
+	*netCfg = j.Network
+	if j.Device != "" {
+		netCfg.Device = j.Device
+	}
+	return netCfg
+}
+
+// GetAppArgs returns the command starting the application, wrapped with the container runtime if the
+// job uses a container. The compatibility of the host MPI implementation with the image is checked.
+func (j *Job) GetAppArgs() ([]string, error) {
+	if j.Container == nil {
+		return append([]string{j.App.BinPath}, j.App.BinArgs...), nil
+	}
+	var hostMPI *implem.Info
+	if j.MPICfg != nil {
+		hostMPI = &j.MPICfg.Implem
+	}
+	err := j.Container.CheckHostMPI(hostMPI)
+	if err != nil {
+		return nil, err
+	}
+	return j.Container.GetExecArgs(j.App.BinPath, j.App.BinArgs)
+}
+
+// GetSoftwareEnv returns the software environment to set up to run the job, including RequiredModules
+func (j *Job) GetSoftwareEnv() *softenv.Env {
+	if len(j.RequiredModules) == 0 {
+		return j.SoftwareEnv
+	}
+	env := softenv.FromModules(j.RequiredModules)
+	if j.SoftwareEnv != nil {
+		env.Steps = append(env.Steps, j.SoftwareEnv.Steps...)
+	}
+	return env
+}
+
+// GetRankOutputDir returns the path to the directory where each rank writes its output with PerRankOutput,
+// or an empty string if the output is tagged with the rank
+func (j *Job) GetRankOutputDir() string {
+	if j.RankOutputDir == "" || filepath.IsAbs(j.RankOutputDir) || j.RunDir == "" {
+		return j.RankOutputDir
+	}
+	return filepath.Join(j.RunDir, j.RankOutputDir)
+}
+
+// AddCleanUp adds a function to call when the job is cleaned up, after the ones that were previously set
+func (j *Job) AddCleanUp(fn CleanUpFn) {
+	prevCleanUp := j.CleanUp
+	if prevCleanUp == nil {
+		j.CleanUp = fn
+		return
+	}
+	j.CleanUp = func(args ...interface{}) error {
+		err := prevCleanUp(args...)
+		errFn := fn(args...)
+		if err != nil {
+			return err
+		}
+		return errFn
+	}
+}
+
 func (j *Job) SetTimestamp() {
 	if j.ExecutionTimestamp == "" {
 		j.ExecutionTimestamp = timestamp.Now()
@@ -0,0 +1,158 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package job
+
+import (
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mpi"
+)
+
+func TestGetEnv(t *testing.T) {
+	environ := []string{"HOME=/home/user", "PATH=/usr/local/bin:/usr/bin", "LD_LIBRARY_PATH=/usr/local/lib", "SECRET=1"}
+	var j Job
+	j.MPICfg = &mpi.Config{Implem: implem.Info{ID: implem.OMPI, InstallDir: "/opt/ompi"}}
+	j.CustomEnv = map[string]string{"UCX_LOG_LEVEL": "info", "HOME": "/tmp"}
+
+	tests := []struct {
+		mode      string
+		allowlist []string
+		expected  string
+	}{
+		{
+			mode:     EnvInherit,
+			expected: "HOME=/tmp PATH=/opt/ompi/bin:/usr/local/bin:/usr/bin LD_LIBRARY_PATH=/opt/ompi/lib:/usr/local/lib SECRET=1 UCX_LOG_LEVEL=info",
+		},
+		{
+			mode:     EnvClear,
+			expected: "PATH=/opt/ompi/bin:/usr/bin:/bin LD_LIBRARY_PATH=/opt/ompi/lib HOME=/tmp UCX_LOG_LEVEL=info",
+		},
+		{
+			mode:      EnvAllowlist,
+			allowlist: []string{"PATH", "MISSING"},
+			expected:  "PATH=/opt/ompi/bin:/usr/local/bin:/usr/bin LD_LIBRARY_PATH=/opt/ompi/lib HOME=/tmp UCX_LOG_LEVEL=info",
+		},
+	}
+	for _, tt := range tests {
+		j.EnvMode = tt.mode
+		j.EnvAllowlist = tt.allowlist
+		env, err := j.GetEnv(environ)
+		if err != nil {
+			t.Fatalf("%s: GetEnv() failed: %s", tt.mode, err)
+		}
+		if strings.Join(env, " ") != tt.expected {
+			t.Fatalf("%s: GetEnv() returned %q instead of %q", tt.mode, strings.Join(env, " "), tt.expected)
+		}
+	}
+
+	j.EnvMode = "unknown"
+	_, err := j.GetEnv(environ)
+	if err == nil {
+		t.Fatalf("GetEnv() succeeded with an invalid mode")
+	}
+}
+
+func TestAccountingFromProcess(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "exit 3")
+	start := time.Now()
+	err := cmd.Run()
+	if err == nil {
+		t.Fatalf("the command did not fail")
+	}
+	a := AccountingFromProcess(cmd.ProcessState, start, time.Now(), []string{"node01"})
+	if a.State != StateFailed || a.ExitCode != 3 || a.Signal != 0 || a.JobID == 0 {
+		t.Fatalf("invalid accounting record: %+v", a)
+	}
+
+	cmd = exec.Command("/bin/sh", "-c", "kill -KILL $$")
+	_ = cmd.Run()
+	a = AccountingFromProcess(cmd.ProcessState, start, time.Now(), nil)
+	if a.State != StateFailed || a.Signal != int(syscall.SIGKILL) {
+		t.Fatalf("invalid accounting record: %+v", a)
+	}
+}
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		state    string
+		exitCode int
+		signal   int
+		failure  string
+	}{
+		{state: StateCompleted, failure: FailureNone},
+		{state: StateCompleted, exitCode: 1, failure: FailureApplication},
+		{state: StateFailed, exitCode: 1, failure: FailureApplication},
+		{state: StateTimeout, signal: 15, failure: FailureScheduler},
+		{state: StateOOM, failure: FailureScheduler},
+		{state: StateNodeFail, failure: FailureScheduler},
+		{state: StateCancelled, failure: FailureScheduler},
+		{state: "RUNNING", failure: FailurePending},
+		{state: "PENDING", failure: FailurePending},
+		{state: "", failure: FailurePending},
+	}
+	for _, tt := range tests {
+		failure := ClassifyFailure(tt.state, tt.exitCode, tt.signal)
+		if failure != tt.failure {
+			t.Fatalf("ClassifyFailure(%s, %d, %d) returned %q instead of %q", tt.state, tt.exitCode, tt.signal, failure, tt.failure)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, Backoff: time.Second, BackoffFactor: 2, MaxBackoff: 5 * time.Second}
+	expectedBackoffs := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	for idx, expected := range expectedBackoffs {
+		if p.GetBackoff(idx+1) != expected {
+			t.Fatalf("GetBackoff(%d) returned %s instead of %s", idx+1, p.GetBackoff(idx+1), expected)
+		}
+	}
+
+	tests := []struct {
+		result    Result
+		retryable bool
+	}{
+		{Result{State: StateCompleted}, false},
+		{Result{Failure: FailureSubmission}, true},
+		{Result{State: StateNodeFail, Failure: FailureScheduler}, true},
+		{Result{State: StateOOM, Failure: FailureScheduler}, false},
+		{Result{State: StateFailed, ExitCode: 1, Failure: FailureApplication}, false},
+	}
+	for _, tt := range tests {
+		if p.IsRetryable(&tt.result) != tt.retryable {
+			t.Fatalf("IsRetryable(%+v) returned %v", tt.result, !tt.retryable)
+		}
+	}
+
+	p.Retryable = []string{FailureApplication}
+	if p.IsRetryable(&Result{Failure: FailureSubmission}) || !p.IsRetryable(&Result{State: StateFailed, Failure: FailureApplication}) {
+		t.Fatalf("IsRetryable() does not use the list of retryable failures")
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := NewLineWriter(func(line string) {
+		lines = append(lines, line)
+	})
+	for _, s := range []string{"Hello ", "world\nfrom rank 0\n", "", "last"} {
+		n, err := w.Write([]byte(s))
+		if err != nil || n != len(s) {
+			t.Fatalf("Write() returned %d, %v", n, err)
+		}
+	}
+	if strings.Join(lines, "|") != "Hello world|from rank 0" {
+		t.Fatalf("invalid lines: %q", lines)
+	}
+	w.Flush()
+	if len(lines) != 3 || lines[2] != "last" {
+		t.Fatalf("the last line was not flushed: %q", lines)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package job
+
+import "fmt"
+
+const (
+	// FailureNone means that the job completed successfully
+	FailureNone = ""
+
+	// FailurePending means that the job has not reached a final state yet (e.g., pending, running) or that its
+	// final state is unknown; the job is not considered successful
+	FailurePending = "pending"
+
+	// FailureSubmission means that the job could not be submitted or started
+	FailureSubmission = "submission"
+
+	// FailureApplication means that the application exited with an error or was terminated by a signal
+	FailureApplication = "application"
+
+	// FailureScheduler means that the job was terminated by the job manager (e.g., time limit reached, node failure)
+	FailureScheduler = "scheduler"
+)
+
+// Result is the structured result of a job
+type Result struct {
+	// State is the final state of the job (e.g., StateCompleted, StateTimeout)
+	State string
+
+	// ExitCode is the exit code of the application
+	ExitCode int
+
+	// Signal is the signal that terminated the application, 0 if none
+	Signal int
+
+	// Failure is the class of failure of the job (e.g., FailureApplication), FailureNone if the job succeeded
+	Failure string
+
+	// Err is the error returned when running the job, if any
+	Err error
+
+	// Accounting is the accounting record of the job, if available
+	Accounting *Accounting
+
+	// Attempts is the history of the attempts to run the job when a retry policy is used, the last one being this result
+	Attempts []Attempt
+}
+
+// ClassifyFailure returns the class of failure of a job based on its final state, exit code and signal.
+// Only final states are classified; other states (e.g., PENDING, RUNNING) give FailurePending.
+func ClassifyFailure(state string, exitCode int, signal int) string {
+	switch state {
+	case StateTimeout, StateOOM, StateNodeFail, StateCancelled, StatePreempted, StateBootFail, StateDeadline:
+		return FailureScheduler
+	case StateFailed:
+		return FailureApplication
+	case StateCompleted:
+		if exitCode != 0 || signal != 0 {
+			return FailureApplication
+		}
+		return FailureNone
+	}
+	return FailurePending
+}
+
+// IsFinal checks whether the result is the one of a job that reached a final state, i.e., a job that is
+// neither pending nor running
+func (r *Result) IsFinal() bool {
+	return r.Failure != FailurePending
+}
+
+// Success checks whether the job succeeded
+func (r *Result) Success() bool {
+	return r.Failure == FailureNone && r.Err == nil
+}
+
+// String returns a human-readable description of the result
+func (r *Result) String() string {
+	if len(r.Attempts) > 1 {
+		last := *r
+		last.Attempts = nil
+		return fmt.Sprintf("%s (after %d attempts)", last.String(), len(r.Attempts))
+	}
+	switch r.Failure {
+	case FailureNone:
+		return fmt.Sprintf("job %s", r.State)
+	case FailurePending:
+		if r.State == "" {
+			return "job not completed (unknown state)"
+		}
+		return fmt.Sprintf("job not completed (state: %s)", r.State)
+	case FailureSubmission:
+		return fmt.Sprintf("job submission failed: %s", r.Err)
+	case FailureScheduler:
+		return fmt.Sprintf("job terminated by the job manager: %s", r.State)
+	}
+	if r.Signal != 0 {
+		return fmt.Sprintf("application terminated by signal %d (state: %s)", r.Signal, r.State)
+	}
+	return fmt.Sprintf("application failed with exit code %d (state: %s)", r.ExitCode, r.State)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package job
+
+import "time"
+
+// DefaultRetryable is the list of failure classes and states that are retried when a retry policy does not specify any:
+// jobs that could not be submitted, e.g., sbatch socket timeouts, and jobs terminated because of a node failure,
+// a preemption or their time limit
+var DefaultRetryable = []string{FailureSubmission, StateNodeFail, StatePreempted, StateTimeout}
+
+// RetryPolicy specifies when and how a failed job is submitted again
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times the job is submitted, including the first attempt
+	MaxAttempts int
+
+	// Backoff is the delay before the second attempt
+	Backoff time.Duration
+
+	// BackoffFactor is the factor applied to the delay after each attempt (1 if not set, i.e., constant delay)
+	BackoffFactor float64
+
+	// MaxBackoff is the maximum delay between two attempts (optional)
+	MaxBackoff time.Duration
+
+	// Retryable is the list of failure classes (e.g., FailureSubmission) and states (e.g., StateNodeFail)
+	// of the jobs to retry; DefaultRetryable if not set
+	Retryable []string
+}
+
+// Attempt is the record of an attempt to run a job
+type Attempt struct {
+	// Number is the number of the attempt, starting at 1
+	Number int
+
+	// JobID is the identifier of the job for this attempt, if any
+	JobID int
+
+	// ExecutionTimestamp is the timestamp of the job for this attempt, used to name its output files
+	ExecutionTimestamp string
+
+	// OutputFile is the path to the file with the output (stdout) of this attempt, when the job manager writes one
+	OutputFile string
+
+	// ErrorFile is the path to the file with stderr of this attempt, when the job manager writes one
+	ErrorFile string
+
+	// Start is when the attempt was submitted
+	Start time.Time
+
+	// End is when the attempt completed
+	End time.Time
+
+	// Result is the result of the attempt
+	Result *Result
+}
+
+// IsRetryable checks whether a job with a given result must be retried, regardless of the number of attempts
+func (p *RetryPolicy) IsRetryable(r *Result) bool {
+	if r.Success() {
+		return false
+	}
+	retryable := p.Retryable
+	if len(retryable) == 0 {
+		retryable = DefaultRetryable
+	}
+	for _, c := range retryable {
+		if c == r.Failure || c == r.State {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBackoff returns the delay to wait after a given attempt (starting at 1) before the next one
+func (p *RetryPolicy) GetBackoff(attempt int) time.Duration {
+	factor := p.BackoffFactor
+	if factor <= 0 {
+		factor = 1
+	}
+	delay := float64(p.Backoff)
+	for i := 1; i < attempt; i++ {
+		delay *= factor
+		if p.MaxBackoff > 0 && delay >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package job
+
+import (
+	"bytes"
+	"sync"
+)
+
+// LineFn is a "function pointer" called for every line of output of a job, without the end of line
+type LineFn func(line string)
+
+// LineWriter is a writer calling a function for every complete line written to it, e.g., to use
+// a callback as Job.Stdout or Job.Stderr
+type LineWriter struct {
+	fn      LineFn
+	mutex   sync.Mutex
+	partial bytes.Buffer
+}
+
+// NewLineWriter returns a writer calling fn for every line written to it
+func NewLineWriter(fn LineFn) *LineWriter {
+	return &LineWriter{fn: fn}
+}
+
+// Write calls the function of the writer for every complete line; an incomplete line is kept until completed
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.partial.Write(p)
+	for {
+		idx := bytes.IndexByte(w.partial.Bytes(), '\n')
+		if idx == -1 {
+			break
+		}
+		line := string(w.partial.Next(idx + 1))
+		w.fn(line[:len(line)-1])
+	}
+	return len(p), nil
+}
+
+// Flush calls the function of the writer for the last line, if it is not terminated by an end of line
+func (w *LineWriter) Flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.partial.Len() > 0 {
+		w.fn(w.partial.String())
+		w.partial.Reset()
+	}
+}
+
+// RankOutput is the output of a job split by rank, when the job is run with PerRankOutput
+type RankOutput struct {
+	// Stdout is the output of each rank, keyed by rank
+	Stdout map[int]string
+
+	// Stderr is stderr of each rank, keyed by rank
+	Stderr map[int]string
+}
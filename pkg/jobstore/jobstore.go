@@ -0,0 +1,598 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package jobstore records the jobs that are submitted in a local directory, so they can be queried,
+// including after the process that submitted them is gone. Records are stored as JSON lines in a single
+// file; every update appends the complete record and the last line of a given record wins. The file can be
+// compacted to only keep the last version of each record (see Store.Compact).
+package jobstore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gvallee/go_hpc_jobmgr/pkg/app"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
+)
+
+const (
+	// StateSubmitted is the state of a job that was submitted and did not complete yet
+	StateSubmitted = "SUBMITTED"
+
+	// StateSubmitFailed is the state of a job that could not be submitted or started
+	StateSubmitFailed = "SUBMIT_FAILED"
+
+	// storeFileName is the name of the file where the records are stored
+	storeFileName = "jobs.jsonl"
+
+	// lockFileName is the name of the file locked while the records are read or updated. The file of the
+	// records is not locked itself since it is replaced when the store is compacted.
+	lockFileName = "jobs.lock"
+
+	// keyTimeFormat is the format of the time in the keys of the records
+	keyTimeFormat = "20060102T150405"
+)
+
+// Spec is the specification of a recorded job
+type Spec struct {
+	// App is the application of the job
+	App app.Info
+
+	// NP is the number of ranks
+	NP int
+
+	// NNodes is the number of nodes
+	NNodes int
+
+	// Partition is the partition the job was submitted to, if any
+	Partition string
+
+	// MPIID is the identifier of the MPI implementation used to run the job, if any
+	MPIID string
+
+	// MPIVersion is the version of the MPI implementation used to run the job, if any
+	MPIVersion string
+
+	// MPIDir is the directory where the MPI implementation is installed, if any
+	MPIDir string
+
+	// NonBlocking specifies whether the job was submitted without waiting for its completion
+	NonBlocking bool
+
+	// RunDir is the directory from which the job was launched
+	RunDir string
+
+	// PerRankOutput specifies whether the output of each rank was kept separate
+	PerRankOutput bool
+
+	// RankOutputDir is the directory where each rank wrote its output, if any
+	RankOutputDir string
+
+	// OutputDir is the directory where the output files of the job are written and its artifacts collected
+	OutputDir string
+
+	// Artifacts is the list of files produced by the job to collect in its output directory, if any
+	Artifacts []string
+
+	// Archive specifies how the files of the job are archived once it completed, if requested
+	Archive *job.ArchiveSpec
+}
+
+// Transition is a change of the state of a recorded job
+type Transition struct {
+	// State is the new state of the job
+	State string
+
+	// Time is when the state of the job changed
+	Time time.Time
+}
+
+// Record is the record of the submission of a job
+type Record struct {
+	// Key is the unique identifier of the record in the store
+	Key string
+
+	// Name is the name of the job
+	Name string
+
+	// Backend is the identifier of the job manager used to submit the job (e.g., slurm)
+	Backend string
+
+	// JobID is the identifier of the job assigned by the job manager, 0 if none
+	JobID int
+
+	// Attempt is the number of the attempt when the job is retried, 0 otherwise
+	Attempt int
+
+	// ExecutionTimestamp is the timestamp of the job, used to name its output files
+	ExecutionTimestamp string
+
+	// BatchScript is the path to the batch script of the job, if any
+	BatchScript string
+
+	// OutputFile is the path to the file with the output (stdout) of the job, if any
+	OutputFile string
+
+	// ErrorFile is the path to the file with stderr of the job, if any
+	ErrorFile string
+
+	// Archive is the path to the archive of the files of the job, once archived
+	Archive string
+
+	// Spec is the specification of the job
+	Spec Spec
+
+	// State is the current state of the job (e.g., StateSubmitted, COMPLETED)
+	State string
+
+	// ExitCode is the exit code of the job, once completed
+	ExitCode int
+
+	// Transitions is the history of the states of the job
+	Transitions []Transition
+}
+
+// Query specifies which records to return; empty fields match all the records
+type Query struct {
+	// Name is the name of the jobs
+	Name string
+
+	// Backend is the identifier of the job manager
+	Backend string
+
+	// State is the current state of the jobs
+	State string
+
+	// Since only matches the jobs submitted at or after a given time
+	Since time.Time
+}
+
+// Store is a set of records stored in a directory. The records are indexed in memory as they are read from the
+// file of the store, which is only read from where it was left off; the store is locked while the file is read
+// or updated so the store can be shared by several processes.
+type Store struct {
+	// Dir is the directory where the records are stored
+	Dir string
+
+	mutex sync.Mutex
+
+	// records are the records read from the file, in the order they were added
+	records []*Record
+
+	// index gives the position of each record in records, based on its key
+	index map[string]int
+
+	// offset is the position in the file up to which the records were read
+	offset int64
+
+	// lineNum is the number of lines read from the file
+	lineNum int
+
+	// fileInfo identifies the file that was read, to detect when it is replaced
+	fileInfo os.FileInfo
+}
+
+var (
+	// stores are the stores opened in the process, based on their directory
+	stores = make(map[string]*Store)
+
+	storesMutex sync.Mutex
+)
+
+// DefaultDir returns the default directory of the store, in the home directory of the user
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "go_hpc_jobmgr", "jobs")
+	}
+	return filepath.Join(home, ".go_hpc_jobmgr", "jobs")
+}
+
+// Open opens the store in a directory, which is created if it does not exist. The same store is returned
+// every time a given directory is opened.
+func Open(dir string) (*Store, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("undefined job store directory")
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the absolute path of %s: %w", dir, err)
+	}
+
+	storesMutex.Lock()
+	defer storesMutex.Unlock()
+	if s, ok := stores[absDir]; ok {
+		return s, nil
+	}
+	err = os.MkdirAll(absDir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %s: %w", dir, err)
+	}
+	s := &Store{Dir: dir, index: make(map[string]int)}
+	stores[absDir] = s
+	return s, nil
+}
+
+// NewKey returns a new unique key for a record
+func NewKey() string {
+	b := make([]byte, 4)
+	_, err := rand.Read(b)
+	if err != nil {
+		return time.Now().Format(keyTimeFormat) + "-" + strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return time.Now().Format(keyTimeFormat) + "-" + hex.EncodeToString(b)
+}
+
+// SetState changes the state of a record and adds the transition to its history, if the state is different
+func (r *Record) SetState(state string, t time.Time) {
+	if r.State == state {
+		return
+	}
+	r.State = state
+	r.Transitions = append(r.Transitions, Transition{State: state, Time: t})
+}
+
+// Submitted returns when the job was submitted
+func (r *Record) Submitted() time.Time {
+	if len(r.Transitions) == 0 {
+		return time.Time{}
+	}
+	return r.Transitions[0].Time
+}
+
+// clone returns a copy of a record that can be modified without changing the record it is copied from
+func (r *Record) clone() *Record {
+	c := *r
+	c.Spec.App.BinArgs = append([]string(nil), r.Spec.App.BinArgs...)
+	c.Spec.App.ExpectedOutputs = append([]app.ExpectedOutput(nil), r.Spec.App.ExpectedOutputs...)
+	c.Spec.Artifacts = append([]string(nil), r.Spec.Artifacts...)
+	if r.Spec.Archive != nil {
+		archive := *r.Spec.Archive
+		c.Spec.Archive = &archive
+	}
+	c.Transitions = append([]Transition(nil), r.Transitions...)
+	return &c
+}
+
+func (s *Store) path() string {
+	return filepath.Join(s.Dir, storeFileName)
+}
+
+// lockedFile is the file of the store, opened while the store is locked
+type lockedFile struct {
+	*os.File
+
+	lock *os.File
+}
+
+// Close closes the file and unlocks the store
+func (f *lockedFile) Close() error {
+	err := f.File.Close()
+	f.lock.Close()
+	return err
+}
+
+// openFile locks the store, exclusively when the file of the store is opened to be updated, in which case
+// the file is created if it does not exist, and opens the file of the store
+func (s *Store) openFile(update bool) (*lockedFile, error) {
+	lockPath := filepath.Join(s.Dir, lockFileName)
+	lock, err := os.OpenFile(lockPath, os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %w", lockPath, err)
+	}
+	how := syscall.LOCK_SH
+	flags := os.O_RDONLY
+	if update {
+		how = syscall.LOCK_EX
+		flags = os.O_RDWR | os.O_APPEND | os.O_CREATE
+	}
+	err = syscall.Flock(int(lock.Fd()), how)
+	if err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("unable to lock %s: %w", lockPath, err)
+	}
+	f, err := os.OpenFile(s.path(), flags, 0644)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return &lockedFile{File: f, lock: lock}, nil
+}
+
+// reset forgets the records read from the file of the store
+func (s *Store) reset() {
+	s.records = nil
+	s.index = make(map[string]int)
+	s.offset = 0
+	s.lineNum = 0
+	s.fileInfo = nil
+}
+
+// add indexes a record, which replaces the previous record with the same key
+func (s *Store) add(r *Record) {
+	if i, ok := s.index[r.Key]; ok {
+		s.records[i] = r
+		return
+	}
+	s.index[r.Key] = len(s.records)
+	s.records = append(s.records, r)
+}
+
+// refresh reads the records added to the file of the store since it was last read. Invalid lines, e.g., left
+// by a process that crashed while writing a record, are skipped.
+func (s *Store) refresh(f *lockedFile) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("unable to stat %s: %w", s.path(), err)
+	}
+	if s.fileInfo == nil || !os.SameFile(s.fileInfo, info) || info.Size() < s.offset {
+		s.reset()
+	}
+	s.fileInfo = info
+	if info.Size() == s.offset {
+		return nil
+	}
+
+	_, err = f.Seek(s.offset, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", s.path(), err)
+	}
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A line that is not terminated is read once it is complete
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %w", s.path(), err)
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			r := new(Record)
+			err = json.Unmarshal(line, r)
+			if err == nil && r.Key == "" {
+				err = fmt.Errorf("undefined record key")
+			}
+			if err == nil {
+				s.add(r)
+			} else {
+				log.Printf("[WARN] skipping invalid record at line %d of %s: %s", s.lineNum+1, s.path(), err)
+			}
+		}
+		s.offset += int64(len(line))
+		s.lineNum++
+	}
+}
+
+// load reads the records added to the file of the store since it was last read
+func (s *Store) load() error {
+	f, err := s.openFile(false)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.reset()
+			return nil
+		}
+		return fmt.Errorf("unable to open %s: %w", s.path(), err)
+	}
+	defer f.Close()
+	return s.refresh(f)
+}
+
+// write appends a record to the file of the store, which must be opened to be updated and up to date
+func (s *Store) write(f *lockedFile, r *Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("unable to encode record %s: %w", r.Key, err)
+	}
+	line = append(line, '\n')
+	// A line left incomplete by a process that crashed while writing it is terminated, so the record is on its
+	// own line
+	if s.fileInfo.Size() > s.offset {
+		line = append([]byte{'\n'}, line...)
+	}
+	_, err = f.Write(line)
+	if err != nil {
+		return fmt.Errorf("unable to write to %s: %w", s.path(), err)
+	}
+	return s.refresh(f)
+}
+
+// Put adds or updates a record
+func (s *Store) Put(r *Record) error {
+	if r.Key == "" {
+		return fmt.Errorf("undefined record key")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f, err := s.openFile(true)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", s.path(), err)
+	}
+	defer f.Close()
+	err = s.refresh(f)
+	if err != nil {
+		return err
+	}
+	return s.write(f, r)
+}
+
+// Update changes the record with a given key with a function, which returns whether the record changed, and
+// records the change. No other update of the store, including from another process, happens in between.
+func (s *Store) Update(key string, fn func(*Record) bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f, err := s.openFile(true)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", s.path(), err)
+	}
+	defer f.Close()
+	err = s.refresh(f)
+	if err != nil {
+		return err
+	}
+	i, ok := s.index[key]
+	if !ok {
+		return fmt.Errorf("job %s not found", key)
+	}
+	r := s.records[i].clone()
+	if !fn(r) {
+		return nil
+	}
+	return s.write(f, r)
+}
+
+// Compact rewrites the file of the store with the last version of each record only, since every update of a
+// record appends the complete record to the file. Invalid lines are dropped.
+func (s *Store) Compact() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f, err := s.openFile(true)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", s.path(), err)
+	}
+	defer f.Close()
+	err = s.refresh(f)
+	if err != nil {
+		return err
+	}
+
+	// The file is replaced once complete; the store is still locked
+	tmpPath := s.path() + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", tmpPath, err)
+	}
+	defer os.Remove(tmpPath)
+	defer tmp.Close()
+	w := bufio.NewWriter(tmp)
+	for _, r := range s.records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("unable to encode record %s: %w", r.Key, err)
+		}
+		_, err = w.Write(append(line, '\n'))
+		if err != nil {
+			return fmt.Errorf("unable to write to %s: %w", tmpPath, err)
+		}
+	}
+	err = w.Flush()
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write to %s: %w", tmpPath, err)
+	}
+	err = os.Rename(tmpPath, s.path())
+	if err != nil {
+		return fmt.Errorf("unable to replace %s: %w", s.path(), err)
+	}
+	s.reset()
+	return nil
+}
+
+// All returns all the records, in the order they were added
+func (s *Store) All() ([]*Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var records []*Record
+	for _, r := range s.records {
+		records = append(records, r.clone())
+	}
+	return records, nil
+}
+
+// List returns the records matching a query, in the order they were added
+func (s *Store) List(q *Query) ([]*Record, error) {
+	records, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	var matches []*Record
+	for _, r := range records {
+		if q.Name != "" && r.Name != q.Name {
+			continue
+		}
+		if q.Backend != "" && r.Backend != q.Backend {
+			continue
+		}
+		if q.State != "" && r.State != q.State {
+			continue
+		}
+		if !q.Since.IsZero() && r.Submitted().Before(q.Since) {
+			continue
+		}
+		matches = append(matches, r)
+	}
+	return matches, nil
+}
+
+// Get returns the record with a given key
+func (s *Store) Get(key string) (*Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	i, ok := s.index[key]
+	if !ok {
+		return nil, fmt.Errorf("job %s not found", key)
+	}
+	return s.records[i].clone(), nil
+}
+
+// FindByJobID returns the most recent record of a job with a given job manager and job ID
+func (s *Store) FindByJobID(backend string, jobID int) (*Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if s.records[i].JobID == jobID && (backend == "" || s.records[i].Backend == backend) {
+			return s.records[i].clone(), nil
+		}
+	}
+	return nil, fmt.Errorf("job %d not found", jobID)
+}
+
+// Find returns the record with a given key or, if the identifier is a number, the most recent record with that job ID
+func (s *Store) Find(id string) (*Record, error) {
+	r, err := s.Get(id)
+	if err == nil {
+		return r, nil
+	}
+	jobID, convErr := strconv.Atoi(id)
+	if convErr != nil {
+		return nil, err
+	}
+	return s.FindByJobID("", jobID)
+}
+
+// UpdateState changes the state of a record
+func (s *Store) UpdateState(key string, state string) error {
+	return s.Update(key, func(r *Record) bool {
+		r.SetState(state, time.Now())
+		return true
+	})
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package jobstore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() failed: %s", err)
+	}
+	records, err := s.All()
+	if err != nil || len(records) != 0 {
+		t.Fatalf("All() returned %v, %v for an empty store", records, err)
+	}
+
+	submitted := time.Now().Add(-time.Hour)
+	r1 := Record{Key: NewKey(), Name: "ring", Backend: "slurm", JobID: 42}
+	r1.SetState(StateSubmitted, submitted)
+	r2 := Record{Key: NewKey(), Name: "hello", Backend: "native"}
+	r2.SetState(StateSubmitted, time.Now())
+	r2.SetState("COMPLETED", time.Now())
+	for _, r := range []*Record{&r1, &r2} {
+		err = s.Put(r)
+		if err != nil {
+			t.Fatalf("Put() failed: %s", err)
+		}
+	}
+	if r1.Key == r2.Key {
+		t.Fatalf("NewKey() returned the same key twice: %s", r1.Key)
+	}
+
+	err = s.UpdateState(r1.Key, "RUNNING")
+	if err != nil {
+		t.Fatalf("UpdateState() failed: %s", err)
+	}
+	records, err = s.All()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("All() returned %v, %v", records, err)
+	}
+	if records[0].State != "RUNNING" || len(records[0].Transitions) != 2 || !records[0].Submitted().Equal(submitted) {
+		t.Fatalf("the update of %s is not recorded: %+v", r1.Key, records[0])
+	}
+
+	r, err := s.Find("42")
+	if err != nil || r.Key != r1.Key {
+		t.Fatalf("Find() returned %+v, %v", r, err)
+	}
+	r, err = s.Find(r2.Key)
+	if err != nil || r.Name != "hello" {
+		t.Fatalf("Find() returned %+v, %v", r, err)
+	}
+	_, err = s.Find("43")
+	if err == nil {
+		t.Fatalf("Find() succeeded with an unknown job")
+	}
+
+	tests := []struct {
+		query    Query
+		expected int
+	}{
+		{Query{}, 2},
+		{Query{Backend: "slurm"}, 1},
+		{Query{State: "COMPLETED"}, 1},
+		{Query{Name: "ring", State: "COMPLETED"}, 0},
+		{Query{Since: submitted.Add(time.Minute)}, 1},
+	}
+	for _, tt := range tests {
+		records, err := s.List(&tt.query)
+		if err != nil || len(records) != tt.expected {
+			t.Fatalf("List(%+v) returned %d records instead of %d (%v)", tt.query, len(records), tt.expected, err)
+		}
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() failed: %s", err)
+	}
+	s2, err := Open(dir)
+	if err != nil || s2 != s {
+		t.Fatalf("Open() returned a different store for %s (%v)", dir, err)
+	}
+	r := Record{Key: NewKey(), Name: "counter"}
+	err = s.Put(&r)
+	if err != nil {
+		t.Fatalf("Put() failed: %s", err)
+	}
+
+	// Another process has its own store, the updates are serialized by locking the file
+	other := &Store{Dir: dir, index: make(map[string]int)}
+	const numUpdates = 50
+	var wg sync.WaitGroup
+	for _, store := range []*Store{s, s, other, other} {
+		wg.Add(1)
+		go func(store *Store) {
+			defer wg.Done()
+			for i := 0; i < numUpdates; i++ {
+				err := store.Update(r.Key, func(r *Record) bool {
+					r.ExitCode++
+					return true
+				})
+				if err != nil {
+					t.Errorf("Update() failed: %s", err)
+					return
+				}
+			}
+		}(store)
+	}
+	wg.Wait()
+
+	for _, store := range []*Store{s, other} {
+		records, err := store.All()
+		if err != nil || len(records) != 1 || records[0].ExitCode != 4*numUpdates {
+			t.Fatalf("All() returned %+v, %v", records, err)
+		}
+	}
+}
+
+func TestIncompleteRecord(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() failed: %s", err)
+	}
+	r := Record{Key: NewKey(), Name: "ring"}
+	r.SetState(StateSubmitted, time.Now())
+	err = s.Put(&r)
+	if err != nil {
+		t.Fatalf("Put() failed: %s", err)
+	}
+
+	// A process crashed while writing a record
+	f, err := os.OpenFile(filepath.Join(dir, storeFileName), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("unable to open the file of the store: %s", err)
+	}
+	_, err = f.WriteString(`{"Key":"crashed","Na`)
+	f.Close()
+	if err != nil {
+		t.Fatalf("unable to write to the file of the store: %s", err)
+	}
+
+	// The next records are still readable, the incomplete line is skipped
+	err = s.UpdateState(r.Key, "RUNNING")
+	if err != nil {
+		t.Fatalf("UpdateState() failed: %s", err)
+	}
+	other := &Store{Dir: dir, index: make(map[string]int)}
+	for _, store := range []*Store{s, other} {
+		records, err := store.All()
+		if err != nil || len(records) != 1 || records[0].State != "RUNNING" {
+			t.Fatalf("All() returned %+v, %v", records, err)
+		}
+	}
+
+	// Only the last version of each record is kept once compacted
+	err = s.Compact()
+	if err != nil {
+		t.Fatalf("Compact() failed: %s", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, storeFileName))
+	if err != nil || strings.Count(string(content), "\n") != 1 {
+		t.Fatalf("invalid content of the compacted store: %q (%v)", content, err)
+	}
+	for _, store := range []*Store{s, other} {
+		records, err := store.All()
+		if err != nil || len(records) != 1 || records[0].State != "RUNNING" || len(records[0].Transitions) != 2 {
+			t.Fatalf("All() returned %+v, %v after compaction", records, err)
+		}
+	}
+	err = other.Put(&Record{Key: NewKey(), Name: "hello"})
+	if err != nil {
+		t.Fatalf("Put() failed after compaction: %s", err)
+	}
+	records, err := s.All()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("All() returned %+v, %v", records, err)
+	}
+}
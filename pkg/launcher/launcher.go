@@ -45,54 +45,60 @@ func Load() (sys.Config, jm.JM, error) {
 	return cfg, jobmgr, nil
 }
 
-/*
-func checkOutput(output string, expected string) bool {
-	return strings.Contains(output, expected)
+// Run executes a job with a specific version of MPI on the host and validates its output and exit code
+// against what the application is expected to produce (see Validate).
+// This is a blocking function, it returns when the job has completed
+func Run(j *job.Job, hostMPI *mpi.Config, jobmgr *jm.JM, sysCfg *sys.Config, args []string) (results.Result, advexec.Result) {
+	jobRes, execRes := RunWithResult(j, hostMPI, jobmgr, sysCfg, args)
+	return CheckResult(j, jobRes, &execRes), execRes
 }
 
-func checkJobOutput(output string, expectedOutput string, jobInfo *job.Job) bool {
-	if jobInfo.NP > 0 {
-		expected := strings.ReplaceAll(expectedOutput, "#NP", strconv.Itoa(jobInfo.NP))
-		for i := 0; i < jobInfo.NP; i++ {
-			curExpectedOutput := strings.ReplaceAll(expected, "#RANK", strconv.Itoa(i))
-			if checkOutput(output, curExpectedOutput) {
-				return true
-			}
+// CheckResult validates the result of a job returned by RunWithResult against what the application
+// is expected to produce (see Validate)
+func CheckResult(j *job.Job, jobRes *job.Result, execRes *advexec.Result) results.Result {
+	var expRes results.Result
+
+	// The output of the application can only be validated if it ran to completion; a non-blocking job
+	// only needs to be submitted
+	if (!jobRes.Success() && jobRes.Failure != job.FailureApplication) || j.NonBlocking {
+		expRes.Pass = jobRes.Success() || (j.NonBlocking && jobRes.Err == nil && !jobRes.IsFinal())
+		if !expRes.Pass {
+			expRes.Note = fmt.Sprintf("[ERROR] %s - stdout: %s - stderr: %s\n", jobRes, execRes.Stdout, execRes.Stderr)
 		}
-		return false
+		return expRes
 	}
-	return checkOutput(output, expectedOutput)
-}
 
-func expectedOutput(stdout string, stderr string, appInfo *app.Info, jobInfo *job.Job) bool {
-	if appInfo.ExpectedRankOutput == "" {
-		log.Println("App does not define any expected output, skipping check...")
-		return true
+	v, err := Validate(&j.App, j.NP, execRes.Stdout, execRes.Stderr, jobRes.ExitCode)
+	if err != nil {
+		expRes.Pass = false
+		expRes.Note = fmt.Sprintf("[ERROR] unable to validate the output: %s\n", err)
+		return expRes
 	}
-
-	// The output can be on stderr or stdout, we just cannot know in advanced.
-	// For instance, some MPI applications sends output to stderr by default
-	matched := checkJobOutput(stdout, appInfo.ExpectedRankOutput, jobInfo)
-	if !matched {
-		matched = checkJobOutput(stderr, appInfo.ExpectedRankOutput, jobInfo)
+	expRes.Pass = v.Pass
+	if !v.Pass {
+		expRes.Note = fmt.Sprintf("[ERROR] %s\n%s\n - stdout: %s - stderr: %s\n", jobRes, v.Summary(), execRes.Stdout, execRes.Stderr)
+	} else if len(v.Patterns) > 0 {
+		expRes.Note = v.Summary()
 	}
-
-	return matched
+	return expRes
 }
-*/
-
-// Run executes a job with a specific version of MPI on the host.
-// This is a blocking function, it returns when the job has completed
-func Run(j *job.Job, hostMPI *mpi.Config, jobmgr *jm.JM, sysCfg *sys.Config, args []string) (results.Result, advexec.Result) {
-	var execRes advexec.Result
-	var expRes results.Result
-	expRes.Pass = true
-	errorMsg := ""
 
+// prepareJob sets the MPI configuration of a job and default values that make sense when no arguments are specified
+func prepareJob(j *job.Job, hostMPI *mpi.Config, args []string) error {
 	if hostMPI != nil {
 		j.MPICfg = new(mpi.Config)
 		j.MPICfg.Implem = hostMPI.Implem
 		j.MPICfg.UserMpirunArgs = hostMPI.UserMpirunArgs
+		j.MPICfg.Params = hostMPI.Params
+		j.MPICfg.ParamsAsEnv = hostMPI.ParamsAsEnv
+		j.MPICfg.VersionConstraint = hostMPI.VersionConstraint
+	}
+
+	if j.MPICfg != nil {
+		err := j.MPICfg.CheckVersion()
+		if err != nil {
+			return fmt.Errorf("MPI requirement not met: %w", err)
+		}
 	}
 
 	if len(args) == 0 {
@@ -106,19 +112,62 @@ func Run(j *job.Job, hostMPI *mpi.Config, jobmgr *jm.JM, sysCfg *sys.Config, arg
 	} else {
 		j.Args = append(j.Args, args...)
 	}
+	return nil
+}
+
+// RunWithResult executes a job with a specific version of MPI on the host and returns its structured result,
+// which gives the exit code of the application, the final state of the job and the class of failure, if any.
+// This is a blocking function, it returns when the job has completed
+func RunWithResult(j *job.Job, hostMPI *mpi.Config, jobmgr *jm.JM, sysCfg *sys.Config, args []string) (*job.Result, advexec.Result) {
+	err := prepareJob(j, hostMPI, args)
+	if err != nil {
+		var execRes advexec.Result
+		execRes.Err = err
+		return &job.Result{Err: execRes.Err, Failure: job.FailureSubmission}, execRes
+	}
 
 	// We submit the job
-	execRes = jobmgr.Submit(j, sysCfg)
-	if execRes.Err != nil {
-		// The command simply failed and the Go runtime caught it
-		expRes.Pass = false
-		errorMsg = fmt.Sprintf("[ERROR] Command failed - stdout: %s - stderr: %s - err: %s\n", execRes.Stdout, execRes.Stderr, execRes.Err)
-		log.Printf("%s", errorMsg)
+	jobRes, execRes := jobmgr.SubmitWithRetry(j, sysCfg)
+	if !jobRes.Success() {
+		log.Printf("[ERROR] %s - stdout: %s - stderr: %s - err: %s\n", jobRes, execRes.Stdout, execRes.Stderr, execRes.Err)
 	}
 
-	if !expRes.Pass {
-		expRes.Note = errorMsg
+	return jobRes, execRes
+}
+
+// RunQueue executes a set of jobs with a specific version of MPI on the host through a submission queue,
+// which limits the number of jobs pending or running per partition (see jm.Queue), and validates the output
+// of each job (see Run). The results are returned in the order of the jobs.
+// This is a blocking function, it returns when all the jobs have completed
+func RunQueue(jobs []*job.Job, hostMPI *mpi.Config, jobmgr *jm.JM, sysCfg *sys.Config, q *jm.Queue) ([]results.Result, []advexec.Result) {
+	res := make([]results.Result, len(jobs))
+	execRes := make([]advexec.Result, len(jobs))
+	var entries []*jm.QueueEntry
+	var idxs []int
+	for idx, j := range jobs {
+		err := prepareJob(j, hostMPI, nil)
+		if err != nil {
+			execRes[idx].Err = err
+			res[idx] = CheckResult(j, &job.Result{Err: err, Failure: job.FailureSubmission}, &execRes[idx])
+			continue
+		}
+		entries = append(entries, q.Add(j))
+		idxs = append(idxs, idx)
 	}
 
-	return expRes, execRes
+	err := q.Run(jobmgr, sysCfg)
+	if err != nil {
+		for _, idx := range idxs {
+			execRes[idx].Err = err
+			res[idx] = CheckResult(jobs[idx], &job.Result{Err: err, Failure: job.FailureSubmission}, &execRes[idx])
+		}
+		return res, execRes
+	}
+
+	for i, e := range entries {
+		idx := idxs[i]
+		execRes[idx] = e.Exec
+		res[idx] = CheckResult(e.Job, e.Result, &execRes[idx])
+	}
+	return res, execRes
 }
@@ -14,6 +14,7 @@ import (
 	"os/exec"
 	"testing"
 
+	"github.com/gvallee/go_hpc_jobmgr/pkg/app"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/jm"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/job"
 )
@@ -55,3 +56,49 @@ func TestSlurmLaunch(t *testing.T) {
 
 	fmt.Printf("Note: %s\n", res.Note)
 }
+
+func TestValidate(t *testing.T) {
+	appInfo := app.Info{
+		ExpectedOutputs: []app.ExpectedOutput{
+			{Pattern: "Hello from rank #RANK/#NP", PerRank: true},
+			{Pattern: `^Elapsed: [0-9.]+s$`, Match: app.MatchRegexp, Stream: app.StreamStdout},
+			{Pattern: "WARNING", Stream: app.StreamStderr},
+		},
+	}
+	stdout := "Hello from rank 0/3\nHello from rank 2/3\nElapsed: 1.5s"
+	stderr := "Hello from rank 1/3\nWARNING: low memory\n"
+
+	v, err := Validate(&appInfo, 3, stdout, stderr, 0)
+	if err != nil {
+		t.Fatalf("Validate() failed: %s", err)
+	}
+	if !v.Pass || len(v.Patterns) != 3 || len(v.Patterns[0].MatchedRanks) != 3 {
+		t.Fatalf("Validate() returned %+v", v)
+	}
+
+	v, err = Validate(&appInfo, 4, stdout, stderr, 1)
+	if err != nil {
+		t.Fatalf("Validate() failed: %s", err)
+	}
+	if v.Pass || v.ExitCodeMatched || v.Patterns[0].Matched {
+		t.Fatalf("Validate() returned %+v", v)
+	}
+	expected := "unexpected exit code: 1\n\"Hello from rank #RANK/#NP\" not matched for rank(s) 0,1,2,3\nmatched \"^Elapsed: [0-9.]+s$\"\nmatched \"WARNING\""
+	if v.Summary() != expected {
+		t.Fatalf("Summary() returned %q instead of %q", v.Summary(), expected)
+	}
+
+	// The regular expression only applies to stdout, while the matching line is in stderr
+	appInfo.ExpectedOutputs = []app.ExpectedOutput{{Pattern: `^WARNING: low memory$`, Match: app.MatchRegexp, Stream: app.StreamStdout}}
+	appInfo.ExpectedExitCode = 1
+	v, err = Validate(&appInfo, 3, stdout, stderr, 1)
+	if err != nil || v.Pass || !v.ExitCodeMatched {
+		t.Fatalf("Validate() returned %+v, %v", v, err)
+	}
+
+	appInfo.ExpectedOutputs = []app.ExpectedOutput{{Pattern: "(", Match: app.MatchRegexp}}
+	_, err = Validate(&appInfo, 3, stdout, stderr, 1)
+	if err == nil {
+		t.Fatalf("Validate() succeeded with an invalid regular expression")
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package launcher
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gvallee/go_hpc_jobmgr/pkg/app"
+)
+
+// PatternResult is the result of the check of an expected output
+type PatternResult struct {
+	// Expected is the expected output that was checked
+	Expected app.ExpectedOutput
+
+	// Matched specifies whether the expected output was found (from all the ranks when checked per rank)
+	Matched bool
+
+	// MatchedRanks is the list of ranks whose output was found, when checked per rank
+	MatchedRanks []int
+
+	// MissingRanks is the list of ranks whose output was not found, when checked per rank
+	MissingRanks []int
+}
+
+// Validation is the result of the validation of the execution of an application
+type Validation struct {
+	// Pass specifies whether all the expectations are met
+	Pass bool
+
+	// ExitCode is the exit code of the application
+	ExitCode int
+
+	// ExitCodeMatched specifies whether the exit code is the expected one
+	ExitCodeMatched bool
+
+	// Patterns is the result of the check of each expected output
+	Patterns []PatternResult
+}
+
+func matchOutput(expected *app.ExpectedOutput, pattern string, stdout string, stderr string) (bool, error) {
+	var outputs []string
+	switch expected.Stream {
+	case app.StreamAny:
+		// The output can be on stderr or stdout, we just cannot know in advance.
+		// For instance, some MPI applications send output to stderr by default
+		outputs = []string{stdout, stderr}
+	case app.StreamStdout:
+		outputs = []string{stdout}
+	case app.StreamStderr:
+		outputs = []string{stderr}
+	default:
+		return false, fmt.Errorf("invalid stream: %s", expected.Stream)
+	}
+
+	switch expected.Match {
+	case "", app.MatchSubstring:
+		for _, o := range outputs {
+			if strings.Contains(o, pattern) {
+				return true, nil
+			}
+		}
+	case app.MatchRegexp:
+		// ^ and $ match at the beginning and end of lines
+		re, err := regexp.Compile("(?m)" + pattern)
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression %s: %w", pattern, err)
+		}
+		for _, o := range outputs {
+			if re.MatchString(o) {
+				return true, nil
+			}
+		}
+	default:
+		return false, fmt.Errorf("invalid match type: %s", expected.Match)
+	}
+	return false, nil
+}
+
+func checkExpectedOutput(expected *app.ExpectedOutput, np int, stdout string, stderr string) (PatternResult, error) {
+	res := PatternResult{Expected: *expected}
+	pattern := strings.ReplaceAll(expected.Pattern, app.NPTag, strconv.Itoa(np))
+	if !expected.PerRank {
+		var err error
+		res.Matched, err = matchOutput(expected, pattern, stdout, stderr)
+		return res, err
+	}
+
+	if np <= 0 {
+		return res, fmt.Errorf("the number of ranks is required to check outputs per rank")
+	}
+	for rank := 0; rank < np; rank++ {
+		rankPattern := strings.ReplaceAll(pattern, app.RankTag, strconv.Itoa(rank))
+		matched, err := matchOutput(expected, rankPattern, stdout, stderr)
+		if err != nil {
+			return res, err
+		}
+		if matched {
+			res.MatchedRanks = append(res.MatchedRanks, rank)
+		} else {
+			res.MissingRanks = append(res.MissingRanks, rank)
+		}
+	}
+	res.Matched = len(res.MissingRanks) == 0
+	return res, nil
+}
+
+// Validate checks the output and exit code of an application against what it is expected to produce
+func Validate(appInfo *app.Info, np int, stdout string, stderr string, exitCode int) (*Validation, error) {
+	v := &Validation{ExitCode: exitCode}
+	v.ExitCodeMatched = exitCode == appInfo.ExpectedExitCode
+	v.Pass = v.ExitCodeMatched
+	for idx := range appInfo.ExpectedOutputs {
+		res, err := checkExpectedOutput(&appInfo.ExpectedOutputs[idx], np, stdout, stderr)
+		if err != nil {
+			return nil, err
+		}
+		if !res.Matched {
+			v.Pass = false
+		}
+		v.Patterns = append(v.Patterns, res)
+	}
+	return v, nil
+}
+
+func ranksToString(ranks []int) string {
+	var strs []string
+	for _, r := range ranks {
+		strs = append(strs, strconv.Itoa(r))
+	}
+	return strings.Join(strs, ",")
+}
+
+// Summary returns a human-readable summary of the validation
+func (v *Validation) Summary() string {
+	var lines []string
+	if !v.ExitCodeMatched {
+		lines = append(lines, fmt.Sprintf("unexpected exit code: %d", v.ExitCode))
+	}
+	for _, p := range v.Patterns {
+		switch {
+		case p.Matched:
+			lines = append(lines, fmt.Sprintf("matched %q", p.Expected.Pattern))
+		case p.Expected.PerRank:
+			lines = append(lines, fmt.Sprintf("%q not matched for rank(s) %s", p.Expected.Pattern, ranksToString(p.MissingRanks)))
+		default:
+			lines = append(lines, fmt.Sprintf("%q not matched", p.Expected.Pattern))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package mapping
+
+import "fmt"
+
+const (
+	// BindCore binds each rank to a core
+	BindCore = "core"
+
+	// BindHWThread binds each rank to a hardware thread
+	BindHWThread = "hwthread"
+
+	// BindSocket binds each rank to a socket
+	BindSocket = "socket"
+
+	// BindNUMA binds each rank to a NUMA domain
+	BindNUMA = "numa"
+
+	// BindNone does not bind ranks
+	BindNone = "none"
+
+	// RankCore assigns consecutive ranks to consecutive cores
+	RankCore = "core"
+
+	// RankSlot assigns consecutive ranks to consecutive slots
+	RankSlot = "slot"
+
+	// RankNode assigns consecutive ranks to different nodes, in a round-robin fashion
+	RankNode = "node"
+
+	// RankSocket assigns consecutive ranks to different sockets, in a round-robin fashion
+	RankSocket = "socket"
+
+	// RankNUMA assigns consecutive ranks to different NUMA domains, in a round-robin fashion
+	RankNUMA = "numa"
+)
+
+// Spec is an implementation-neutral description of how ranks are placed and bound.
+// Each MPI implementation translates it into its own arguments. All fields are optional.
+type Spec struct {
+	// RanksPerNode is the number of ranks per node
+	RanksPerNode int
+
+	// RanksPerSocket is the number of ranks per socket
+	RanksPerSocket int
+
+	// RanksPerNUMA is the number of ranks per NUMA domain
+	RanksPerNUMA int
+
+	// BindTo specifies what each rank is bound to (e.g., BindCore)
+	BindTo string
+
+	// RankBy specifies the order in which ranks are assigned (e.g., RankCore)
+	RankBy string
+}
+
+// ForLayout returns a spec that evenly distributes np ranks over nnodes nodes, with
+// ranks bound to and ordered by cores. The number of ranks per node is not set if
+// the number of ranks or nodes is unknown.
+func ForLayout(np int, nnodes int) *Spec {
+	s := new(Spec)
+	if np > 0 && nnodes > 0 {
+		s.RanksPerNode = np / nnodes
+		if s.RanksPerNode == 0 {
+			s.RanksPerNode = 1
+		}
+	}
+	s.BindTo = BindCore
+	s.RankBy = RankCore
+	return s
+}
+
+// Validate checks that a spec is consistent
+func (s *Spec) Validate() error {
+	if s.RanksPerNode < 0 || s.RanksPerSocket < 0 || s.RanksPerNUMA < 0 {
+		return fmt.Errorf("invalid negative number of ranks")
+	}
+	n := 0
+	for _, v := range []int{s.RanksPerNode, s.RanksPerSocket, s.RanksPerNUMA} {
+		if v > 0 {
+			n++
+		}
+	}
+	if n > 1 {
+		return fmt.Errorf("only one of ranks per node, socket or NUMA domain can be specified")
+	}
+
+	switch s.BindTo {
+	case "", BindCore, BindHWThread, BindSocket, BindNUMA, BindNone:
+	default:
+		return fmt.Errorf("invalid binding: %s", s.BindTo)
+	}
+
+	switch s.RankBy {
+	case "", RankCore, RankSlot, RankNode, RankSocket, RankNUMA:
+	default:
+		return fmt.Errorf("invalid ranking: %s", s.RankBy)
+	}
+
+	return nil
+}
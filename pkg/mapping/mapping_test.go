@@ -0,0 +1,48 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package mapping
+
+import "testing"
+
+func TestForLayout(t *testing.T) {
+	s := ForLayout(8, 2)
+	if s.RanksPerNode != 4 || s.BindTo != BindCore || s.RankBy != RankCore {
+		t.Fatalf("ForLayout() returned %+v", s)
+	}
+
+	// No division by zero when the number of nodes is unknown
+	s = ForLayout(8, 0)
+	if s.RanksPerNode != 0 {
+		t.Fatalf("ForLayout() returned %+v", s)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []Spec{
+		{},
+		{RanksPerNode: 4, BindTo: BindCore, RankBy: RankCore},
+		{RanksPerSocket: 2, BindTo: BindNone},
+	}
+	for _, s := range valid {
+		err := s.Validate()
+		if err != nil {
+			t.Fatalf("Validate() failed for %+v: %s", s, err)
+		}
+	}
+
+	invalid := []Spec{
+		{RanksPerNode: 4, RanksPerSocket: 2},
+		{RanksPerNUMA: -1},
+		{BindTo: "board"},
+		{RankBy: "random"},
+	}
+	for _, s := range invalid {
+		err := s.Validate()
+		if err == nil {
+			t.Fatalf("Validate() succeeded for %+v", s)
+		}
+	}
+}
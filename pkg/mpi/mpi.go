@@ -9,6 +9,7 @@ package mpi
 import (
 	"fmt"
 	"log"
+	"os"
 	"os/exec"
 	"path/filepath"
 
@@ -19,9 +20,20 @@ import (
 	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/openmpi"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/app"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/mapping"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/nodelist"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/params"
 	"github.com/gvallee/go_hpc_jobmgr/pkg/sys"
 )
 
+const (
+	// ResourceManagerSlurm is the identifier of Slurm as a resource manager
+	ResourceManagerSlurm = "slurm"
+
+	// ResourceManagerPBS is the identifier of PBS as a resource manager
+	ResourceManagerPBS = "pbs"
+)
+
 // Config represents a configuration of MPI for a target platform
 // todo: revisit this, i do not think we actually need it, i think it would make everything
 // easier if we were dealing with the different elements separately
@@ -31,6 +43,34 @@ type Config struct {
 
 	// UserMpirunArgs is a list of extra arguments defined by the user to pass to the mpirun commands
 	UserMpirunArgs []string
+
+	// Params is an optional set of parameters of the MPI implementation (e.g., MCA parameters) set by
+	// the user, which override the default parameters
+	Params *params.Set
+
+	// ParamsAsEnv specifies whether the parameters are exported as environment variables in batch
+	// scripts, or set in the environment of mpirun with the native job manager, rather than passed
+	// as mpirun arguments. The variables that mpirun does not forward to the ranks by itself are
+	// still forwarded with mpirun arguments (see GetEnvForwardArgs).
+	ParamsAsEnv bool
+
+	// VersionConstraint is an optional requirement on the version of the MPI implementation, e.g., ">=4.1,<5"
+	VersionConstraint string
+}
+
+// CheckVersion checks that the MPI implementation satisfies the version constraint of the configuration, if any
+func (c *Config) CheckVersion() error {
+	if c.VersionConstraint == "" {
+		return nil
+	}
+	ok, err := c.Implem.Satisfies(c.VersionConstraint)
+	if err != nil {
+		return fmt.Errorf("unable to check the version of %s: %w", c.Implem.ID, err)
+	}
+	if !ok {
+		return fmt.Errorf("%s %s does not satisfy %s", c.Implem.ID, c.Implem.Version, c.VersionConstraint)
+	}
+	return nil
 }
 
 // GetPathToMpirun returns the path to mpirun based a configuration of MPI
@@ -53,19 +93,174 @@ func GetPathToMpirun(mpiCfg *implem.Info) (string, error) {
 	return path, nil
 }
 
-// GetMpirunArgs returns the arguments required by a mpirun
-func GetMpirunArgs(myHostMPICfg *implem.Info, app *app.Info, sysCfg *sys.Config, netCfg *network.Config, mpirunArgs []string) ([]string, error) {
-	var extraArgs []string
+// GetParams returns the parameters (e.g., MCA parameters) of the MPI implementation for the target platform,
+// overridden by the parameters set by the user, either through userParams or as part of mpirunArgs. The
+// arguments of mpirunArgs that do not set a parameter are returned as they are. An error is returned if
+// the user sets a parameter required by the configuration to a different value (e.g., pml ob1 while UCX
+// transports are requested).
+func GetParams(myHostMPICfg *implem.Info, sysCfg *sys.Config, netCfg *network.Config, mapSpec *mapping.Spec, userParams *params.Set, mpirunArgs []string) (*params.Set, []string, error) {
+	var set *params.Set
+	var argParams *params.Set
+	var otherArgs []string
+	var err error
 
-	// We really do not want to do this but MPICH is being picky about args so for now, it will do the job.
 	switch myHostMPICfg.ID {
 	case implem.OMPI:
-		extraArgs = append(extraArgs, openmpi.GetExtraMpirunArgs(sysCfg, netCfg, mpirunArgs)...)
+		err = openmpi.ValidateNetwork(netCfg, myHostMPICfg.OMPICapabilities)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid network configuration for %s: %w", myHostMPICfg.ID, err)
+		}
+		set = openmpi.GetParams(sysCfg, netCfg, myHostMPICfg.OMPICapabilities)
+		argParams, otherArgs, err = openmpi.ParseParamArgs(mpirunArgs)
 	case implem.MVAPICH2:
-		extraArgs = append(extraArgs, mvapich2.GetExtraMpirunArgs(sysCfg, netCfg, mpirunArgs)...)
+		err = mvapich2.ValidateNetwork(netCfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid network configuration for %s: %w", myHostMPICfg.ID, err)
+		}
+		set = mvapich2.GetParams(sysCfg, netCfg, mapSpec)
+		// MVAPICH2's mpirun is based on Hydra, like MPICH's
+		argParams, otherArgs, err = mpich.ParseParamArgs(mpirunArgs)
+	case implem.MPICH:
+		err = mpich.ValidateNetwork(netCfg, myHostMPICfg.MPICHCapabilities)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid network configuration for %s: %w", myHostMPICfg.ID, err)
+		}
+		set = mpich.GetParams(sysCfg, netCfg, myHostMPICfg.MPICHCapabilities)
+		argParams, otherArgs, err = mpich.ParseParamArgs(mpirunArgs)
+	default:
+		set = params.New()
+		otherArgs = mpirunArgs
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid mpirun arguments: %w", err)
+	}
+
+	err = set.Merge(userParams)
+	if err == nil {
+		err = set.Merge(argParams)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid parameters for %s: %w", myHostMPICfg.ID, err)
 	}
 
-	return extraArgs, nil
+	switch myHostMPICfg.ID {
+	case implem.MVAPICH2:
+		err = mvapich2.ValidateParams(set)
+	case implem.MPICH:
+		err = mpich.ValidateParams(set)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return set, otherArgs, nil
+}
+
+func getMpirunArgs(myHostMPICfg *implem.Info, sysCfg *sys.Config, netCfg *network.Config, mapSpec *mapping.Spec, userParams *params.Set, mpirunArgs []string, paramsAsArgs bool) ([]string, *params.Set, error) {
+	var mappingArgs []string
+
+	set, extraArgs, err := GetParams(myHostMPICfg, sysCfg, netCfg, mapSpec, userParams, mpirunArgs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The arguments of the user that are not parameters come first, as they are, followed by the parameters
+	// (unless set through the environment), the network and the mapping arguments of the implementation
+	switch myHostMPICfg.ID {
+	case implem.OMPI:
+		if paramsAsArgs {
+			extraArgs = append(extraArgs, openmpi.GetParamArgs(set)...)
+		}
+		mappingArgs, err = openmpi.GetMappingArgs(mapSpec)
+	case implem.MVAPICH2:
+		if paramsAsArgs {
+			extraArgs = append(extraArgs, mvapich2.GetParamArgs(set)...)
+		}
+		extraArgs = append(extraArgs, mvapich2.GetInterfaceArgs(netCfg)...)
+		mappingArgs, err = mvapich2.GetMappingArgs(mapSpec)
+	case implem.MPICH:
+		if paramsAsArgs {
+			extraArgs = append(extraArgs, mpich.GetParamArgs(set)...)
+		}
+		extraArgs = append(extraArgs, mpich.GetInterfaceArgs(netCfg)...)
+		mappingArgs, err = mpich.GetMappingArgs(mapSpec)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid mapping for %s: %w", myHostMPICfg.ID, err)
+	}
+	extraArgs = append(extraArgs, mappingArgs...)
+
+	return extraArgs, set, nil
+}
+
+// GetMpirunArgs returns the arguments required by a mpirun, including the parameters of the MPI implementation.
+// mapSpec is optional and describes how ranks are placed and bound. userParams is optional and overrides
+// the default parameters, see GetParams for details.
+func GetMpirunArgs(myHostMPICfg *implem.Info, app *app.Info, sysCfg *sys.Config, netCfg *network.Config, mapSpec *mapping.Spec, userParams *params.Set, mpirunArgs []string) ([]string, error) {
+	args, _, err := getMpirunArgs(myHostMPICfg, sysCfg, netCfg, mapSpec, userParams, mpirunArgs, true)
+	return args, err
+}
+
+// GetMpirunArgsAndParams is similar to GetMpirunArgs but the parameters of the MPI implementation are not
+// part of the returned arguments; they are returned separately so they can be set through the environment,
+// e.g., with params.Set.Exports() in a batch script.
+func GetMpirunArgsAndParams(myHostMPICfg *implem.Info, app *app.Info, sysCfg *sys.Config, netCfg *network.Config, mapSpec *mapping.Spec, userParams *params.Set, mpirunArgs []string) ([]string, *params.Set, error) {
+	return getMpirunArgs(myHostMPICfg, sysCfg, netCfg, mapSpec, userParams, mpirunArgs, false)
+}
+
+// GetEnvForwardArgs returns the mpirun arguments required for the parameters set in the environment of mpirun
+// to reach the ranks, including on remote nodes. Hydra (MPICH, MVAPICH2) forwards the whole environment.
+func GetEnvForwardArgs(myHostMPICfg *implem.Info, set *params.Set) []string {
+	switch myHostMPICfg.ID {
+	case implem.OMPI:
+		return openmpi.GetEnvForwardArgs(set)
+	}
+	return nil
+}
+
+// WriteHostfile writes the hostfile for a list of nodes in the format expected by the MPI implementation
+// and returns the mpirun arguments to use it
+func WriteHostfile(myHostMPICfg *implem.Info, nodes []nodelist.Node, path string) ([]string, error) {
+	var content string
+	var args []string
+	switch myHostMPICfg.ID {
+	case implem.OMPI:
+		content = openmpi.GetHostfileContent(nodes)
+		args = openmpi.GetHostfileArgs(path)
+	case implem.MPICH, implem.MVAPICH2:
+		// The mpirun command of MVAPICH2 is Hydra, like MPICH
+		content = mpich.GetHostfileContent(nodes)
+		args = mpich.GetHostfileArgs(path)
+	default:
+		return nil, fmt.Errorf("hostfiles are not supported with %s", myHostMPICfg.ID)
+	}
+
+	err := os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write to file %s: %w", path, err)
+	}
+	return args, nil
+}
+
+// GetLauncherArgs returns the mpirun arguments required to launch a job within a given resource manager
+// (e.g., ResourceManagerSlurm). An empty resource manager means the job is not running within an allocation.
+func GetLauncherArgs(myHostMPICfg *implem.Info, resourceManager string) []string {
+	switch myHostMPICfg.ID {
+	case implem.MPICH:
+		return mpich.GetLaunchArgs(myHostMPICfg.MPICHCapabilities, resourceManager)
+	}
+	return nil
+}
+
+// DetectResourceManager figures out if we are running within an allocation of a resource manager
+// and if so, returns its identifier
+func DetectResourceManager() string {
+	if os.Getenv("SLURM_JOB_ID") != "" {
+		return ResourceManagerSlurm
+	}
+	if os.Getenv("PBS_JOBID") != "" {
+		return ResourceManagerPBS
+	}
+	return ""
 }
 
 // CheckIntegrity checks if a given installation of MPI has been compromised
@@ -77,7 +272,7 @@ func CheckIntegrity(basedir string) error {
 }
 
 // Detect figures out the details about the default MPI implementation
-// that is available
+// that is available. Use Scan to get all the MPI implementations available on the system.
 func Detect() (*implem.Info, error) {
 	mpirunPath, err := exec.LookPath("mpirun")
 	if err != nil {
@@ -85,41 +280,16 @@ func Detect() (*implem.Info, error) {
 	}
 
 	mpiInfo := new(implem.Info)
-	mpiBinDir := filepath.Dir(mpirunPath)
-	// We assume that MPI was not installed in a system directory where binaries
-	// and libraries are in totally different directories
-	if filepath.Base(mpiBinDir) != "bin" {
-		return nil, fmt.Errorf("%s is not a valid MPI installation", mpiBinDir)
+	mpiInfo.InstallDir, err = installDirFromBin(mpirunPath)
+	if err != nil {
+		return nil, err
 	}
-	mpiInfo.InstallDir = filepath.Dir(mpiBinDir)
+	mpiInfo.Source = implem.SourcePath
 
 	return mpiInfo, nil
 }
 
+// DetectFromDir figures out which MPI implementation is installed in a given directory
 func DetectFromDir(dir string) (implem.Info, error) {
-	var m implem.Info
-	id, version, err := openmpi.DetectFromDir(dir, nil)
-	if err == nil {
-		m.ID = id
-		m.Version = version
-		m.InstallDir = dir
-		return m, nil
-	}
-	// Always check for MVAPICH before MPICH since they share some code, otherwise MVAPICH is not correctly detected
-	id, version, err = mvapich2.DetectFromDir(dir, nil)
-	if err == nil {
-		m.ID = id
-		m.Version = version
-		m.InstallDir = dir
-		return m, nil
-	}
-	id, version, err = mpich.DetectFromDir(dir, nil)
-	if err == nil {
-		m.ID = id
-		m.Version = version
-		m.InstallDir = dir
-		return m, nil
-	}
-
-	return m, fmt.Errorf("unable to detect any supported MPI implementation from %s", dir)
+	return implem.DetectFromDir(dir, nil)
 }
@@ -0,0 +1,163 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package mpi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/network"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/nodelist"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/params"
+)
+
+func TestWriteHostfile(t *testing.T) {
+	nodes := []nodelist.Node{{Name: "node01", Slots: 4}, {Name: "node02"}}
+	tests := []struct {
+		id              string
+		expectedContent string
+		expectedArgs    string
+	}{
+		{id: implem.OMPI, expectedContent: "node01 slots=4\nnode02\n", expectedArgs: "--hostfile"},
+		{id: implem.MPICH, expectedContent: "node01:4\nnode02\n", expectedArgs: "-f"},
+		{id: implem.MVAPICH2, expectedContent: "node01:4\nnode02\n", expectedArgs: "-f"},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "hostfile")
+		args, err := WriteHostfile(&implem.Info{ID: tt.id}, nodes, path)
+		if err != nil {
+			t.Fatalf("%s: WriteHostfile() failed: %s", tt.id, err)
+		}
+		if strings.Join(args, " ") != tt.expectedArgs+" "+path {
+			t.Fatalf("%s: WriteHostfile() returned %v", tt.id, args)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: unable to read %s: %s", tt.id, path, err)
+		}
+		if string(content) != tt.expectedContent {
+			t.Fatalf("%s: invalid hostfile content: %q instead of %q", tt.id, content, tt.expectedContent)
+		}
+	}
+}
+
+func TestGetParams(t *testing.T) {
+	info := &implem.Info{ID: implem.OMPI}
+
+	// Without network configuration, the user can override the default PML
+	args, err := GetMpirunArgs(info, nil, nil, nil, nil, nil, []string{"--mca", "pml", "ob1", "--oversubscribe"})
+	if err != nil {
+		t.Fatalf("GetMpirunArgs() failed: %s", err)
+	}
+	expected := "--oversubscribe --mca btl ^openib --mca pml ob1"
+	if strings.Join(args, " ") != expected {
+		t.Fatalf("GetMpirunArgs() returned %q instead of %q", strings.Join(args, " "), expected)
+	}
+
+	// UCX transports are requested so pml ucx is required
+	netCfg := &network.Config{UCXTLS: []string{"rc"}}
+	_, err = GetMpirunArgs(info, nil, nil, netCfg, nil, nil, []string{"--mca", "pml", "ob1"})
+	if err == nil {
+		t.Fatalf("GetMpirunArgs() succeeded with a conflicting PML")
+	}
+
+	userParams := params.New()
+	_ = userParams.SetUser(params.KindMCA, "coll_hcoll_enable", "0")
+	args, set, err := GetMpirunArgsAndParams(info, nil, nil, netCfg, nil, userParams, nil)
+	if err != nil {
+		t.Fatalf("GetMpirunArgsAndParams() failed: %s", err)
+	}
+	if len(args) != 0 {
+		t.Fatalf("GetMpirunArgsAndParams() returned arguments: %v", args)
+	}
+	expected = "OMPI_MCA_btl=^openib OMPI_MCA_pml=ucx UCX_TLS=rc OMPI_MCA_coll_hcoll_enable=0"
+	if strings.Join(set.Env(), " ") != expected {
+		t.Fatalf("GetMpirunArgsAndParams() returned %q instead of %q", strings.Join(set.Env(), " "), expected)
+	}
+
+	// MPICH parameters are environment variables
+	_, err = GetMpirunArgs(&implem.Info{ID: implem.MPICH}, nil, nil, nil, nil, userParams, nil)
+	if err == nil {
+		t.Fatalf("GetMpirunArgs() succeeded with MCA parameters for MPICH")
+	}
+	args, err = GetMpirunArgs(&implem.Info{ID: implem.MPICH}, nil, nil, nil, nil, nil, []string{"-genv", "FOO", "1", "-genv", "BAR=2"})
+	if err != nil {
+		t.Fatalf("GetMpirunArgs() failed: %s", err)
+	}
+	expected = "-genv FOO=1 -genv BAR=2"
+	if strings.Join(args, " ") != expected {
+		t.Fatalf("GetMpirunArgs() returned %q instead of %q", strings.Join(args, " "), expected)
+	}
+
+	// The other arguments of the user are passed through with Hydra (MPICH, MVAPICH2)
+	for _, id := range []string{implem.MPICH, implem.MVAPICH2} {
+		args, err = GetMpirunArgs(&implem.Info{ID: id}, nil, nil, nil, nil, nil, []string{"-ppn", "2", "-genv", "FOO", "1", "-l"})
+		if err != nil {
+			t.Fatalf("GetMpirunArgs() failed with %s: %s", id, err)
+		}
+		joined := strings.Join(args, " ")
+		if !strings.HasPrefix(joined, "-ppn 2 -l ") || !strings.Contains(joined, "-genv FOO=1") {
+			t.Fatalf("GetMpirunArgs() returned %q with %s", joined, id)
+		}
+	}
+}
+
+func TestRankOutput(t *testing.T) {
+	ompi := &implem.Info{ID: implem.OMPI}
+	mpich := &implem.Info{ID: implem.MPICH}
+
+	args, err := GetRankOutputArgs(mpich, "/tmp/out")
+	if err != nil {
+		t.Fatalf("GetRankOutputArgs() failed: %s", err)
+	}
+	expected := "-outfile-pattern /tmp/out/rank.%r.out -errfile-pattern /tmp/out/rank.%r.err"
+	if strings.Join(args, " ") != expected {
+		t.Fatalf("GetRankOutputArgs() returned %v instead of %s", args, expected)
+	}
+	_, err = GetRankOutputArgs(&implem.Info{ID: "unknown"}, "")
+	if err == nil {
+		t.Fatalf("GetRankOutputArgs() succeeded with an unsupported MPI implementation")
+	}
+
+	ranks, err := ParseTaggedOutput(ompi, "[1,1]<stdout>:world\n[1,0]<stdout>: hello\nlauncher message\n[1,1]<stdout>:!\n")
+	if err != nil {
+		t.Fatalf("ParseTaggedOutput() failed: %s", err)
+	}
+	if len(ranks) != 2 || ranks[0] != "hello\n" || ranks[1] != "world\n!\n" {
+		t.Fatalf("ParseTaggedOutput() returned %q", ranks)
+	}
+	ranks, err = ParseTaggedOutput(mpich, "[0] hello\n[12] world\n")
+	if err != nil {
+		t.Fatalf("ParseTaggedOutput() failed: %s", err)
+	}
+	if len(ranks) != 2 || ranks[0] != "hello\n" || ranks[12] != "world\n" {
+		t.Fatalf("ParseTaggedOutput() returned %q", ranks)
+	}
+
+	// Open MPI writes the output of each rank in a sub-directory named after the job
+	dir := t.TempDir()
+	for _, rank := range []string{"rank.0", "rank.1"} {
+		err = os.MkdirAll(filepath.Join(dir, "1", rank), 0755)
+		if err != nil {
+			t.Fatalf("unable to create the output directory: %s", err)
+		}
+		err = os.WriteFile(filepath.Join(dir, "1", rank, "stdout"), []byte(rank), 0644)
+		if err != nil {
+			t.Fatalf("unable to create the output file: %s", err)
+		}
+	}
+	stdoutFiles, stderrFiles, err := GetRankOutputFiles(ompi, dir)
+	if err != nil {
+		t.Fatalf("GetRankOutputFiles() failed: %s", err)
+	}
+	if len(stdoutFiles) != 2 || stdoutFiles[1] != filepath.Join(dir, "1", "rank.1", "stdout") || len(stderrFiles) != 0 {
+		t.Fatalf("GetRankOutputFiles() returned %v and %v", stdoutFiles, stderrFiles)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package mpi
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/mpich"
+	"github.com/gvallee/go_hpc_jobmgr/internal/pkg/openmpi"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
+)
+
+// GetRankOutputArgs returns the mpirun arguments to keep the output of each rank separate. With a directory,
+// each rank writes its output to its own files in the directory (see GetRankOutputFiles); otherwise, each
+// line of output is tagged with the rank (see ParseTaggedOutput).
+func GetRankOutputArgs(myHostMPICfg *implem.Info, dir string) ([]string, error) {
+	switch myHostMPICfg.ID {
+	case implem.OMPI:
+		return openmpi.GetRankOutputArgs(dir), nil
+	case implem.MPICH, implem.MVAPICH2:
+		// The mpirun command of MVAPICH2 is Hydra, like MPICH
+		return mpich.GetRankOutputArgs(dir), nil
+	}
+	return nil, fmt.Errorf("per-rank output is not supported with %s", myHostMPICfg.ID)
+}
+
+// GetRankOutputFiles returns the paths to the files with stdout and stderr of each rank, keyed by rank, that
+// mpirun wrote in dir when using the arguments returned by GetRankOutputArgs
+func GetRankOutputFiles(myHostMPICfg *implem.Info, dir string) (map[int]string, map[int]string, error) {
+	switch myHostMPICfg.ID {
+	case implem.OMPI:
+		return openmpi.GetRankOutputFiles(dir)
+	case implem.MPICH, implem.MVAPICH2:
+		return mpich.GetRankOutputFiles(dir)
+	}
+	return nil, nil, fmt.Errorf("per-rank output is not supported with %s", myHostMPICfg.ID)
+}
+
+// ParseTaggedOutput splits the output of a job, tagged with the rank when using the arguments returned by
+// GetRankOutputArgs without a directory, and returns the output of each rank keyed by rank
+func ParseTaggedOutput(myHostMPICfg *implem.Info, output string) (map[int]string, error) {
+	switch myHostMPICfg.ID {
+	case implem.OMPI:
+		return ParseTaggedLines(openmpi.TaggedOutputRegexp, output), nil
+	case implem.MPICH, implem.MVAPICH2:
+		return ParseTaggedLines(mpich.TaggedOutputRegexp, output), nil
+	}
+	return nil, fmt.Errorf("per-rank output is not supported with %s", myHostMPICfg.ID)
+}
+
+// ParseTaggedLines splits output where lines are tagged with a rank and returns the output of each rank keyed
+// by rank. The regular expression captures the rank and the content of the line; lines not matching it, e.g.,
+// messages from the launcher, are ignored.
+func ParseTaggedLines(re *regexp.Regexp, output string) map[int]string {
+	lines := make(map[int][]string)
+	for _, line := range strings.Split(output, "\n") {
+		m := re.FindStringSubmatch(strings.TrimSuffix(line, "\r"))
+		if len(m) != 3 {
+			continue
+		}
+		rank, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		lines[rank] = append(lines[rank], m[2])
+	}
+	ranks := make(map[int]string)
+	for rank, l := range lines {
+		ranks[rank] = strings.Join(l, "\n") + "\n"
+	}
+	return ranks
+}
@@ -0,0 +1,305 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package mpi
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gvallee/go_exec/pkg/advexec"
+	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
+	"github.com/gvallee/go_util/pkg/util"
+)
+
+const (
+	// defaultScanDepth is the default number of directory levels scanned under a root directory
+	defaultScanDepth = 2
+
+	// spackFindFormat is the format used with 'spack find' to get the name, version and prefix of packages
+	spackFindFormat = "{name}@{version}/{hash:7} {prefix}"
+)
+
+// mpiPackageNames are the names of the modules and Spack packages that are considered when scanning for MPI
+var mpiPackageNames = []string{"openmpi", "ompi", "hpcx", "mpich", "mvapich2", "mvapich"}
+
+// ScanConfig specifies where to look for MPI installations
+type ScanConfig struct {
+	// Path enables the scan of the directories in PATH
+	Path bool
+
+	// Roots is a list of directories under which MPI installations are searched
+	Roots []string
+
+	// Depth is the number of directory levels scanned under each root (2 when not set)
+	Depth int
+
+	// Modules enables the scan of Lmod/Environment Modules
+	Modules bool
+
+	// Spack enables the scan of Spack installations
+	Spack bool
+}
+
+// DefaultScanConfig returns a scan configuration where all the sources are enabled
+func DefaultScanConfig() ScanConfig {
+	return ScanConfig{
+		Path:    true,
+		Modules: true,
+		Spack:   true,
+	}
+}
+
+type scanCandidate struct {
+	dir       string
+	source    string
+	sourceRef string
+}
+
+// installDirFromBin returns the installation directory of MPI based on the path to one of its binaries.
+// We assume that MPI was not installed in a system directory where binaries and libraries are in
+// totally different directories.
+func installDirFromBin(binPath string) (string, error) {
+	binDir := filepath.Dir(binPath)
+	if filepath.Base(binDir) != "bin" {
+		return "", fmt.Errorf("%s is not a valid MPI installation", binDir)
+	}
+	return filepath.Dir(binDir), nil
+}
+
+// getScanKey returns the key identifying an MPI installation found by Scan: the installation directory of
+// its mpirun once symlinks are resolved, so an installation reachable from several directories (e.g., a
+// module and a Spack view) is reported once
+func getScanKey(dir string) string {
+	resolved, err := filepath.EvalSymlinks(filepath.Join(dir, "bin", "mpirun"))
+	if err != nil {
+		return filepath.Clean(dir)
+	}
+	key, err := installDirFromBin(resolved)
+	if err != nil {
+		return filepath.Clean(dir)
+	}
+	return key
+}
+
+func scanPath() []scanCandidate {
+	var candidates []scanCandidate
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		mpirun := filepath.Join(dir, "mpirun")
+		if !util.FileExists(mpirun) {
+			continue
+		}
+		installDir, err := installDirFromBin(mpirun)
+		if err != nil {
+			log.Printf("-> skipping %s: %s", mpirun, err)
+			continue
+		}
+		candidates = append(candidates, scanCandidate{dir: installDir, source: implem.SourcePath, sourceRef: dir})
+	}
+	return candidates
+}
+
+func scanRoot(root string, depth int) []scanCandidate {
+	var candidates []scanCandidate
+	if util.FileExists(filepath.Join(root, "bin", "mpirun")) {
+		candidates = append(candidates, scanCandidate{dir: root, source: implem.SourceDir, sourceRef: root})
+	}
+	if depth == 0 {
+		return candidates
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return candidates
+	}
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == "bin" {
+			continue
+		}
+		candidates = append(candidates, scanRoot(filepath.Join(root, e.Name()), depth-1)...)
+	}
+	return candidates
+}
+
+// isMPIPackageName checks whether the name of a module or Spack package is the name of an MPI implementation.
+// Each component of the name is checked, e.g., "mpi/openmpi-x86_64" on RHEL and Fedora.
+func isMPIPackageName(name string) bool {
+	for _, component := range strings.Split(strings.ToLower(name), "/") {
+		for _, n := range mpiPackageNames {
+			if strings.HasPrefix(component, n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseModuleAvailOutput parses the output of 'module -t avail' and returns the list of MPI modules
+func parseModuleAvailOutput(output string) []string {
+	var modules []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		// Skip empty lines and the lines giving the directory of the modules (e.g., "/opt/modulefiles:")
+		if line == "" || strings.HasSuffix(line, ":") {
+			continue
+		}
+		line = strings.TrimSuffix(line, "(default)")
+		line = strings.TrimSuffix(line, "/")
+		if isMPIPackageName(line) {
+			modules = append(modules, line)
+		}
+	}
+	return modules
+}
+
+var modulePathRegexps = []*regexp.Regexp{
+	// Lmod, e.g., prepend_path("PATH","/opt/openmpi/bin")
+	regexp.MustCompile(`^prepend_path\(\s*"PATH"\s*,\s*"([^"]+)"`),
+	// Environment Modules, e.g., prepend-path PATH /opt/openmpi/bin
+	regexp.MustCompile(`^prepend-path\s+PATH\s+(\S+)`),
+}
+
+// parseModuleShowOutput parses the output of 'module show' and returns the MPI installation directory
+func parseModuleShowOutput(output string) (string, error) {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		for _, re := range modulePathRegexps {
+			m := re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			if filepath.Base(m[1]) == "bin" {
+				return filepath.Dir(m[1]), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no bin directory added to PATH")
+}
+
+func runModuleCmd(args string) (string, error) {
+	var cmd advexec.Advcmd
+	var err error
+	// module is usually a shell function so we need a login shell
+	cmd.BinPath, err = exec.LookPath("bash")
+	if err != nil {
+		return "", err
+	}
+	cmd.CmdArgs = []string{"-lc", "module " + args + " 2>&1"}
+	res := cmd.Run()
+	if res.Err != nil {
+		return "", fmt.Errorf("module %s failed: %w", args, res.Err)
+	}
+	return res.Stdout, nil
+}
+
+func scanModules() []scanCandidate {
+	var candidates []scanCandidate
+	output, err := runModuleCmd("-t avail")
+	if err != nil {
+		log.Printf("-> modules not available: %s", err)
+		return nil
+	}
+	for _, m := range parseModuleAvailOutput(output) {
+		showOutput, err := runModuleCmd("show " + m)
+		if err != nil {
+			log.Printf("-> skipping module %s: %s", m, err)
+			continue
+		}
+		dir, err := parseModuleShowOutput(showOutput)
+		if err != nil {
+			log.Printf("-> skipping module %s: %s", m, err)
+			continue
+		}
+		candidates = append(candidates, scanCandidate{dir: dir, source: implem.SourceModule, sourceRef: m})
+	}
+	return candidates
+}
+
+// parseSpackFindOutput parses the output of 'spack find --format' and returns the MPI installations
+func parseSpackFindOutput(output string) []scanCandidate {
+	var candidates []scanCandidate
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		// Skip empty lines and headers such as "==> 3 installed packages" or "-- linux-rhel8-zen2 / gcc@11.2.0 --"
+		if line == "" || strings.HasPrefix(line, "==>") || strings.HasPrefix(line, "--") {
+			continue
+		}
+		tokens := strings.Fields(line)
+		if len(tokens) != 2 {
+			continue
+		}
+		if !isMPIPackageName(tokens[0]) {
+			continue
+		}
+		candidates = append(candidates, scanCandidate{dir: tokens[1], source: implem.SourceSpack, sourceRef: tokens[0]})
+	}
+	return candidates
+}
+
+func scanSpack() []scanCandidate {
+	var cmd advexec.Advcmd
+	var err error
+	cmd.BinPath, err = exec.LookPath("spack")
+	if err != nil {
+		log.Println("-> Spack not available")
+		return nil
+	}
+	cmd.CmdArgs = []string{"find", "--format", spackFindFormat}
+	res := cmd.Run()
+	if res.Err != nil {
+		log.Printf("-> spack find failed: %s", res.Err)
+		return nil
+	}
+	return parseSpackFindOutput(res.Stdout)
+}
+
+// Scan enumerates all the MPI installations that can be found on the system based on a scan configuration.
+// Each installation is reported only once, with the first source it was found through.
+func Scan(cfg ScanConfig) ([]implem.Info, error) {
+	var candidates []scanCandidate
+	if cfg.Path {
+		candidates = append(candidates, scanPath()...)
+	}
+	depth := cfg.Depth
+	if depth == 0 {
+		depth = defaultScanDepth
+	}
+	for _, root := range cfg.Roots {
+		if !util.IsDir(root) {
+			return nil, fmt.Errorf("%s is not a directory", root)
+		}
+		candidates = append(candidates, scanRoot(root, depth)...)
+	}
+	if cfg.Modules {
+		candidates = append(candidates, scanModules()...)
+	}
+	if cfg.Spack {
+		candidates = append(candidates, scanSpack()...)
+	}
+
+	var mpis []implem.Info
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		dir := filepath.Clean(c.dir)
+		key := getScanKey(dir)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		i, err := implem.DetectFromDir(dir, nil)
+		if err != nil {
+			log.Printf("-> skipping %s: %s", dir, err)
+			continue
+		}
+		i.Source = c.source
+		i.SourceRef = c.sourceRef
+		mpis = append(mpis, i)
+	}
+	return mpis, nil
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package mpi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gvallee/go_hpc_jobmgr/pkg/implem"
+)
+
+func TestParseModuleAvailOutput(t *testing.T) {
+	output := `/opt/apps/modulefiles/Core:
+gcc/11.2.0
+openmpi/4.1.5
+openmpi/5.0.3(default)
+/opt/apps/modulefiles/Compiler/gcc/11.2.0:
+cmake/
+mvapich2/2.3.7
+MPICH/4.1.2
+/usr/share/modulefiles:
+mpi/openmpi-x86_64
+mpi/mpich-x86_64
+pmi/pmix-x86_64
+`
+	expected := []string{"openmpi/4.1.5", "openmpi/5.0.3", "mvapich2/2.3.7", "MPICH/4.1.2", "mpi/openmpi-x86_64", "mpi/mpich-x86_64"}
+	modules := parseModuleAvailOutput(output)
+	if len(modules) != len(expected) {
+		t.Fatalf("parseModuleAvailOutput() returned %v instead of %v", modules, expected)
+	}
+	for idx := range expected {
+		if modules[idx] != expected[idx] {
+			t.Fatalf("parseModuleAvailOutput() returned %v instead of %v", modules, expected)
+		}
+	}
+}
+
+func TestParseModuleShowOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{
+			name: "lmod",
+			output: `------------------------------------------------------------
+   /opt/apps/modulefiles/Core/openmpi/4.1.5.lua:
+------------------------------------------------------------
+whatis("Open MPI 4.1.5")
+prepend_path("MANPATH","/opt/openmpi-4.1.5/share/man")
+prepend_path("PATH","/opt/openmpi-4.1.5/bin")
+prepend_path("LD_LIBRARY_PATH","/opt/openmpi-4.1.5/lib")`,
+			expected: "/opt/openmpi-4.1.5",
+		},
+		{
+			name: "environment modules",
+			output: `-------------------------------------------------------------------
+/usr/share/modulefiles/mpi/mpich-x86_64:
+
+conflict        mpi
+prepend-path    PATH /usr/lib64/mpich/bin
+prepend-path    LD_LIBRARY_PATH /usr/lib64/mpich/lib
+-------------------------------------------------------------------`,
+			expected: "/usr/lib64/mpich",
+		},
+	}
+
+	for _, tt := range tests {
+		dir, err := parseModuleShowOutput(tt.output)
+		if err != nil {
+			t.Fatalf("%s: parseModuleShowOutput() failed: %s", tt.name, err)
+		}
+		if dir != tt.expected {
+			t.Fatalf("%s: parseModuleShowOutput() returned %s instead of %s", tt.name, dir, tt.expected)
+		}
+	}
+
+	_, err := parseModuleShowOutput(`setenv("CC","gcc")`)
+	if err == nil {
+		t.Fatalf("parseModuleShowOutput() succeeded without PATH")
+	}
+}
+
+func TestParseSpackFindOutput(t *testing.T) {
+	output := `==> 3 installed packages
+-- linux-rhel8-zen2 / gcc@11.2.0 ---------------------------------
+openmpi@4.1.5/abcdefg /spack/opt/linux-rhel8-zen2/gcc-11.2.0/openmpi-4.1.5-abcdefg
+hwloc@2.9.1/hijklmn /spack/opt/linux-rhel8-zen2/gcc-11.2.0/hwloc-2.9.1-hijklmn
+mvapich2@2.3.7/opqrstu /spack/opt/linux-rhel8-zen2/gcc-11.2.0/mvapich2-2.3.7-opqrstu
+`
+	candidates := parseSpackFindOutput(output)
+	if len(candidates) != 2 {
+		t.Fatalf("parseSpackFindOutput() returned %d candidates instead of 2: %v", len(candidates), candidates)
+	}
+	if candidates[0].sourceRef != "openmpi@4.1.5/abcdefg" || candidates[0].dir != "/spack/opt/linux-rhel8-zen2/gcc-11.2.0/openmpi-4.1.5-abcdefg" {
+		t.Fatalf("invalid candidate: %+v", candidates[0])
+	}
+	if candidates[1].source != implem.SourceSpack {
+		t.Fatalf("invalid source: %s", candidates[1].source)
+	}
+}
+
+func TestScanRoots(t *testing.T) {
+	root := t.TempDir()
+	installDir := filepath.Join(root, "openmpi", "4.1.5")
+	binDir := filepath.Join(installDir, "bin")
+	err := os.MkdirAll(binDir, 0755)
+	if err != nil {
+		t.Fatalf("unable to create %s: %s", binDir, err)
+	}
+	script := "#!/bin/sh\necho 'Open MPI v4.1.5'\n"
+	for _, bin := range []string{"mpirun", "ompi_info"} {
+		err = os.WriteFile(filepath.Join(binDir, bin), []byte(script), 0755)
+		if err != nil {
+			t.Fatalf("unable to create fake %s: %s", bin, err)
+		}
+	}
+
+	var cfg ScanConfig
+	cfg.Roots = []string{root}
+	mpis, err := Scan(cfg)
+	if err != nil {
+		t.Fatalf("Scan() failed: %s", err)
+	}
+	if len(mpis) != 1 {
+		t.Fatalf("Scan() found %d MPI installation(s) instead of 1", len(mpis))
+	}
+	if mpis[0].ID != implem.OMPI || mpis[0].Version != "4.1.5" || mpis[0].Source != implem.SourceDir || mpis[0].InstallDir != installDir {
+		t.Fatalf("Scan() returned %+v", mpis[0])
+	}
+	// An installation reachable through symlinks is found once; Detect keeps the path it was found with
+	viewDir := filepath.Join(t.TempDir(), "view")
+	err = os.MkdirAll(filepath.Join(viewDir, "bin"), 0755)
+	if err != nil {
+		t.Fatalf("unable to create %s: %s", viewDir, err)
+	}
+	err = os.Symlink(filepath.Join(binDir, "mpirun"), filepath.Join(viewDir, "bin", "mpirun"))
+	if err != nil {
+		t.Fatalf("unable to create a symlink: %s", err)
+	}
+	cfg.Roots = []string{root, viewDir}
+	mpis, err = Scan(cfg)
+	if err != nil || len(mpis) != 1 {
+		t.Fatalf("Scan() returned %+v (%v) with an installation reachable through a symlink", mpis, err)
+	}
+	t.Setenv("PATH", filepath.Join(viewDir, "bin"))
+	info, err := Detect()
+	if err != nil || info.InstallDir != viewDir {
+		t.Fatalf("Detect() returned %+v (%v) instead of %s", info, err, viewDir)
+	}
+}
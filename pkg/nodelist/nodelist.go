@@ -0,0 +1,235 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package nodelist
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SlurmNodeListEnv is the environment variable set by Slurm with the list of nodes of the allocation
+	SlurmNodeListEnv = "SLURM_JOB_NODELIST"
+
+	// SlurmTasksPerNodeEnv is the environment variable set by Slurm with the number of tasks per node
+	SlurmTasksPerNodeEnv = "SLURM_TASKS_PER_NODE"
+
+	// PBSNodefileEnv is the environment variable set by PBS with the path to the file listing the nodes of the allocation
+	PBSNodefileEnv = "PBS_NODEFILE"
+)
+
+// Node represents a node that can be used to run a job
+type Node struct {
+	// Name is the host name of the node
+	Name string
+
+	// Slots is the number of ranks that can be started on the node (0 means it is not specified)
+	Slots int
+}
+
+// splitTopLevel splits a string on commas that are not within brackets
+func splitTopLevel(expr string) ([]string, error) {
+	var tokens []string
+	depth := 0
+	start := 0
+	for idx, c := range expr {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced brackets in %s", expr)
+			}
+		case ',':
+			if depth == 0 {
+				tokens = append(tokens, expr[start:idx])
+				start = idx + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced brackets in %s", expr)
+	}
+	return append(tokens, expr[start:]), nil
+}
+
+// expandRange expands the content of a bracket, e.g., "01-04,07", keeping the zero-padding
+func expandRange(r string) ([]string, error) {
+	var values []string
+	for _, t := range strings.Split(r, ",") {
+		bounds := strings.SplitN(t, "-", 2)
+		if len(bounds) == 1 {
+			values = append(values, t)
+			continue
+		}
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %s: %w", t, err)
+		}
+		last, err := strconv.Atoi(bounds[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %s: %w", t, err)
+		}
+		if last < first {
+			return nil, fmt.Errorf("invalid range %s", t)
+		}
+		width := len(bounds[0])
+		for i := first; i <= last; i++ {
+			values = append(values, fmt.Sprintf("%0*d", width, i))
+		}
+	}
+	return values, nil
+}
+
+func expandHost(expr string) ([]string, error) {
+	open := strings.Index(expr, "[")
+	if open == -1 {
+		return []string{expr}, nil
+	}
+	end := strings.Index(expr[open:], "]")
+	if end == -1 {
+		return nil, fmt.Errorf("unbalanced brackets in %s", expr)
+	}
+	end += open
+	values, err := expandRange(expr[open+1 : end])
+	if err != nil {
+		return nil, err
+	}
+	// The rest of the expression may have other brackets, e.g., rack[1-2]-node[1-4]
+	suffixes, err := expandHost(expr[end+1:])
+	if err != nil {
+		return nil, err
+	}
+	var hosts []string
+	for _, v := range values {
+		for _, s := range suffixes {
+			hosts = append(hosts, expr[:open]+v+s)
+		}
+	}
+	return hosts, nil
+}
+
+// Expand expands a Slurm hostlist expression, e.g., "node[01-04,07],login1", into the list of host names
+func Expand(expr string) ([]string, error) {
+	var hosts []string
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return hosts, nil
+	}
+	tokens, err := splitTopLevel(expr)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		if t == "" {
+			continue
+		}
+		h, err := expandHost(t)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, h...)
+	}
+	return hosts, nil
+}
+
+// expandTasksPerNode expands the Slurm notation for the number of tasks per node, e.g., "2(x3),1" into [2 2 2 1]
+func expandTasksPerNode(str string) ([]int, error) {
+	var tasks []int
+	for _, t := range strings.Split(str, ",") {
+		count := 1
+		if idx := strings.Index(t, "(x"); idx != -1 {
+			var err error
+			count, err = strconv.Atoi(strings.TrimSuffix(t[idx+2:], ")"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid number of tasks per node %s: %w", t, err)
+			}
+			t = t[:idx]
+		}
+		n, err := strconv.Atoi(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number of tasks per node %s: %w", t, err)
+		}
+		for i := 0; i < count; i++ {
+			tasks = append(tasks, n)
+		}
+	}
+	return tasks, nil
+}
+
+// FromSlurm returns the list of nodes based on a Slurm hostlist expression and the optional
+// Slurm notation of the number of tasks per node (e.g., "2(x3),1")
+func FromSlurm(nodeList string, tasksPerNode string) ([]Node, error) {
+	hosts, err := Expand(nodeList)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []int
+	if tasksPerNode != "" {
+		tasks, err = expandTasksPerNode(tasksPerNode)
+		if err != nil {
+			return nil, err
+		}
+		if len(tasks) != len(hosts) {
+			return nil, fmt.Errorf("%d nodes but the number of tasks is specified for %d nodes", len(hosts), len(tasks))
+		}
+	}
+	var nodes []Node
+	for idx, h := range hosts {
+		n := Node{Name: h}
+		if tasks != nil {
+			n.Slots = tasks[idx]
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// FromPBSNodefile returns the list of nodes from a PBS nodefile, where each node appears once per slot
+func FromPBSNodefile(path string) ([]Node, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var nodes []Node
+	index := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" {
+			continue
+		}
+		idx, ok := index[name]
+		if !ok {
+			idx = len(nodes)
+			index[name] = idx
+			nodes = append(nodes, Node{Name: name})
+		}
+		nodes[idx].Slots++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	return nodes, nil
+}
+
+// FromAllocation returns the list of nodes of the allocation we are running in, based on the
+// environment set by Slurm or PBS
+func FromAllocation() ([]Node, error) {
+	if nodeList := os.Getenv(SlurmNodeListEnv); nodeList != "" {
+		return FromSlurm(nodeList, os.Getenv(SlurmTasksPerNodeEnv))
+	}
+	if nodefile := os.Getenv(PBSNodefileEnv); nodefile != "" {
+		return FromPBSNodefile(nodefile)
+	}
+	return nil, fmt.Errorf("not running within a Slurm or PBS allocation")
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package nodelist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{expr: "node01", expected: "node01"},
+		{expr: "node[01-04,07]", expected: "node01,node02,node03,node04,node07"},
+		{expr: "gpu[8-10],login1", expected: "gpu8,gpu9,gpu10,login1"},
+		{expr: "rack[1-2]-n[1,3]", expected: "rack1-n1,rack1-n3,rack2-n1,rack2-n3"},
+		{expr: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		hosts, err := Expand(tt.expr)
+		if err != nil {
+			t.Fatalf("Expand(%s) failed: %s", tt.expr, err)
+		}
+		if strings.Join(hosts, ",") != tt.expected {
+			t.Fatalf("Expand(%s) returned %v instead of %s", tt.expr, hosts, tt.expected)
+		}
+	}
+
+	for _, invalid := range []string{"node[01-04", "node01]", "node[4-1]", "node[a-b]"} {
+		_, err := Expand(invalid)
+		if err == nil {
+			t.Fatalf("Expand(%s) succeeded", invalid)
+		}
+	}
+}
+
+func TestFromSlurm(t *testing.T) {
+	nodes, err := FromSlurm("node[01-03],bigmem1", "2(x3),8")
+	if err != nil {
+		t.Fatalf("FromSlurm() failed: %s", err)
+	}
+	if len(nodes) != 4 || nodes[0].Name != "node01" || nodes[0].Slots != 2 || nodes[3].Name != "bigmem1" || nodes[3].Slots != 8 {
+		t.Fatalf("FromSlurm() returned %v", nodes)
+	}
+
+	_, err = FromSlurm("node[01-03]", "2")
+	if err == nil {
+		t.Fatalf("FromSlurm() succeeded with an inconsistent number of tasks per node")
+	}
+}
+
+func TestFromPBSNodefile(t *testing.T) {
+	nodefile := filepath.Join(t.TempDir(), "nodefile")
+	err := os.WriteFile(nodefile, []byte("node2\nnode2\nnode1\nnode2\n\n"), 0644)
+	if err != nil {
+		t.Fatalf("unable to create %s: %s", nodefile, err)
+	}
+
+	t.Setenv(SlurmNodeListEnv, "")
+	t.Setenv(PBSNodefileEnv, nodefile)
+	nodes, err := FromAllocation()
+	if err != nil {
+		t.Fatalf("FromAllocation() failed: %s", err)
+	}
+	if len(nodes) != 2 || nodes[0] != (Node{Name: "node2", Slots: 3}) || nodes[1] != (Node{Name: "node1", Slots: 1}) {
+		t.Fatalf("FromAllocation() returned %v", nodes)
+	}
+}
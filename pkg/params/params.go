@@ -0,0 +1,204 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package params
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// KindMCA is the kind of Open MPI MCA parameters (e.g., pml=ucx)
+	KindMCA = "mca"
+
+	// KindEnv is the kind of parameters set through environment variables (e.g., MV2_USE_RDMA_CM=0)
+	KindEnv = "env"
+
+	// MCAEnvPrefix is the prefix of the environment variables used to set Open MPI MCA parameters
+	MCAEnvPrefix = "OMPI_MCA_"
+)
+
+const (
+	// PriorityDefault is the priority of parameters that can be silently overridden
+	PriorityDefault = iota
+
+	// PriorityUser is the priority of parameters set by the user. A parameter set by the user
+	// overrides a default and a previous value set by the user.
+	PriorityUser
+
+	// PriorityRequired is the priority of parameters required by the configuration (e.g., pml=ucx
+	// when UCX transports are requested). Setting a required parameter to a different value is a conflict.
+	PriorityRequired
+)
+
+// Param is a parameter of an MPI implementation
+type Param struct {
+	// Kind is the kind of parameter (KindMCA or KindEnv)
+	Kind string
+
+	// Name is the name of the parameter, e.g., pml or UCX_TLS
+	Name string
+
+	// Value is the value of the parameter
+	Value string
+
+	// Priority specifies how the parameter can be overridden (e.g., PriorityDefault)
+	Priority int
+}
+
+// Set is an ordered set of parameters, where each parameter appears only once
+type Set struct {
+	params []Param
+}
+
+// New returns a new empty set of parameters
+func New() *Set {
+	return new(Set)
+}
+
+// EnvName returns the name of the environment variable used to set the parameter
+func (p *Param) EnvName() string {
+	if p.Kind == KindMCA {
+		return MCAEnvPrefix + p.Name
+	}
+	return p.Name
+}
+
+func (s *Set) lookup(kind string, name string) int {
+	for idx := range s.params {
+		if s.params[idx].Kind == kind && s.params[idx].Name == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+// Get returns a parameter of the set
+func (s *Set) Get(kind string, name string) (Param, bool) {
+	if s == nil {
+		return Param{}, false
+	}
+	idx := s.lookup(kind, name)
+	if idx == -1 {
+		return Param{}, false
+	}
+	return s.params[idx], true
+}
+
+// Add adds a parameter to the set. If the parameter is already in the set, the parameter with
+// the highest priority is kept; an error is returned if two different values are set for a
+// parameter and one of them is required.
+func (s *Set) Add(p Param) error {
+	switch p.Kind {
+	case KindMCA, KindEnv:
+	default:
+		return fmt.Errorf("invalid kind of parameter: %s", p.Kind)
+	}
+	if p.Name == "" {
+		return fmt.Errorf("undefined parameter name")
+	}
+
+	idx := s.lookup(p.Kind, p.Name)
+	if idx == -1 {
+		s.params = append(s.params, p)
+		return nil
+	}
+	cur := &s.params[idx]
+	if cur.Value == p.Value {
+		if p.Priority > cur.Priority {
+			cur.Priority = p.Priority
+		}
+		return nil
+	}
+	if cur.Priority == PriorityRequired || p.Priority == PriorityRequired {
+		return fmt.Errorf("conflicting values for %s %s: %s and %s", p.Kind, p.Name, cur.Value, p.Value)
+	}
+	if p.Priority >= cur.Priority {
+		*cur = p
+	}
+	return nil
+}
+
+// SetDefault adds a parameter that can be overridden
+func (s *Set) SetDefault(kind string, name string, value string) {
+	// A default never conflicts
+	_ = s.Add(Param{Kind: kind, Name: name, Value: value, Priority: PriorityDefault})
+}
+
+// SetUser adds a parameter set by the user
+func (s *Set) SetUser(kind string, name string, value string) error {
+	return s.Add(Param{Kind: kind, Name: name, Value: value, Priority: PriorityUser})
+}
+
+// SetRequired adds a parameter required by the configuration
+func (s *Set) SetRequired(kind string, name string, value string) error {
+	return s.Add(Param{Kind: kind, Name: name, Value: value, Priority: PriorityRequired})
+}
+
+// Merge adds all the parameters of another set, in order
+func (s *Set) Merge(other *Set) error {
+	if other == nil {
+		return nil
+	}
+	for _, p := range other.params {
+		err := s.Add(p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Params returns the parameters of the set, in the order they were added
+func (s *Set) Params() []Param {
+	if s == nil {
+		return nil
+	}
+	return append([]Param(nil), s.params...)
+}
+
+// Len returns the number of parameters in the set
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.params)
+}
+
+// Env returns the parameters as environment variables, e.g., OMPI_MCA_pml=ucx
+func (s *Set) Env() []string {
+	var env []string
+	for _, p := range s.Params() {
+		env = append(env, p.EnvName()+"="+p.Value)
+	}
+	return env
+}
+
+// Exports returns the shell commands exporting the parameters as environment variables
+func (s *Set) Exports() string {
+	exports := ""
+	for _, p := range s.Params() {
+		exports += fmt.Sprintf("export %s=%s\n", p.EnvName(), ShellQuote(p.Value))
+	}
+	return exports
+}
+
+// ShellQuote quotes a value so that it is a single word of a shell command, if needed
+func ShellQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n'\"$`\\;&|<>()*?![]{}~#") {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// Parse parses a parameter from its NAME=VALUE form
+func Parse(kind string, str string) (Param, error) {
+	tokens := strings.SplitN(str, "=", 2)
+	if len(tokens) != 2 || tokens[0] == "" {
+		return Param{}, fmt.Errorf("invalid parameter %s, NAME=VALUE expected", str)
+	}
+	return Param{Kind: kind, Name: tokens[0], Value: tokens[1], Priority: PriorityUser}, nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package params
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	set := New()
+	set.SetDefault(KindMCA, "btl", "^openib")
+	set.SetDefault(KindMCA, "pml", "ucx")
+	err := set.SetRequired(KindEnv, "UCX_TLS", "rc,sm")
+	if err != nil {
+		t.Fatalf("SetRequired() failed: %s", err)
+	}
+
+	// A user parameter overrides a default in place
+	err = set.SetUser(KindMCA, "pml", "ob1")
+	if err != nil {
+		t.Fatalf("SetUser() failed: %s", err)
+	}
+	// The same value for a required parameter is not a conflict
+	err = set.SetUser(KindEnv, "UCX_TLS", "rc,sm")
+	if err != nil {
+		t.Fatalf("SetUser() failed: %s", err)
+	}
+	err = set.SetUser(KindEnv, "UCX_TLS", "tcp")
+	if err == nil {
+		t.Fatalf("SetUser() succeeded with a conflicting value for a required parameter")
+	}
+	// A default does not override a user parameter
+	set.SetDefault(KindMCA, "pml", "cm")
+
+	env := strings.Join(set.Env(), " ")
+	expected := "OMPI_MCA_btl=^openib OMPI_MCA_pml=ob1 UCX_TLS=rc,sm"
+	if env != expected {
+		t.Fatalf("Env() returned %q instead of %q", env, expected)
+	}
+
+	err = set.Add(Param{Kind: "unknown", Name: "foo", Value: "bar"})
+	if err == nil {
+		t.Fatalf("Add() succeeded with an invalid kind")
+	}
+}
+
+func TestMergeAndExports(t *testing.T) {
+	set := New()
+	set.SetDefault(KindEnv, "MV2_USE_RDMA_CM", "0")
+	user := New()
+	_ = user.SetUser(KindEnv, "MV2_USE_RDMA_CM", "1")
+	_ = user.SetUser(KindEnv, "MY_OPTS", "a b")
+	err := set.Merge(user)
+	if err != nil {
+		t.Fatalf("Merge() failed: %s", err)
+	}
+	err = set.Merge(nil)
+	if err != nil {
+		t.Fatalf("Merge() failed with a nil set: %s", err)
+	}
+	exports := set.Exports()
+	expected := "export MV2_USE_RDMA_CM=1\nexport MY_OPTS='a b'\n"
+	if exports != expected {
+		t.Fatalf("Exports() returned %q instead of %q", exports, expected)
+	}
+
+	_, err = Parse(KindEnv, "NOVALUE")
+	if err == nil {
+		t.Fatalf("Parse() succeeded without a value")
+	}
+}
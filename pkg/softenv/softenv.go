@@ -0,0 +1,261 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package softenv describes how the software environment of a job is set up (e.g., modules to load,
+// Spack environment to activate) so the same setup can be applied by all the job managers.
+package softenv
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gvallee/go_exec/pkg/advexec"
+	"github.com/gvallee/go_util/pkg/util"
+)
+
+const (
+	// KindModules loads Lmod/Environment Modules modules; Args is the list of modules
+	KindModules = "modules"
+
+	// KindSpackEnv activates a Spack environment; Args is the name of, or the path to, the environment
+	KindSpackEnv = "spack-env"
+
+	// KindSpackLoad loads Spack packages; Args is the list of specs
+	KindSpackLoad = "spack-load"
+
+	// KindSource sources a shell file; Args is the path to the file, optionally followed by its arguments
+	KindSource = "source"
+
+	// envMarker separates the output of the setup from the resulting environment when capturing it
+	envMarker = "__JOBMGR_SOFTENV__"
+)
+
+// Step is a single step of the setup of a software environment
+type Step struct {
+	// Kind is the kind of step (e.g., KindModules)
+	Kind string
+
+	// Args are the arguments of the step, which depend on its kind
+	Args []string
+}
+
+// Env is a software environment, set up by executing its steps in order
+type Env struct {
+	// Purge specifies whether all modules are unloaded before executing the steps
+	Purge bool
+
+	// Steps is the ordered list of steps to set up the environment
+	Steps []Step
+}
+
+// FromModules returns the software environment corresponding to a list of modules, which are loaded
+// after purging the modules that are already loaded
+func FromModules(modules []string) *Env {
+	if len(modules) == 0 {
+		return nil
+	}
+	return &Env{Purge: true, Steps: []Step{{Kind: KindModules, Args: modules}}}
+}
+
+// IsEmpty checks whether the software environment has anything to set up
+func (e *Env) IsEmpty() bool {
+	return e == nil || (!e.Purge && len(e.Steps) == 0)
+}
+
+func (e *Env) usesModules() bool {
+	if e.Purge {
+		return true
+	}
+	for _, s := range e.Steps {
+		if s.Kind == KindModules {
+			return true
+		}
+	}
+	return false
+}
+
+// Commands returns the shell commands setting up the software environment
+func (e *Env) Commands() ([]string, error) {
+	var cmds []string
+	if e.IsEmpty() {
+		return cmds, nil
+	}
+	if e.Purge {
+		cmds = append(cmds, "module purge")
+	}
+	for _, s := range e.Steps {
+		if len(s.Args) == 0 {
+			return nil, fmt.Errorf("no argument for the %s step", s.Kind)
+		}
+		switch s.Kind {
+		case KindModules:
+			cmds = append(cmds, "module load "+strings.Join(s.Args, " "))
+		case KindSpackEnv:
+			if len(s.Args) != 1 {
+				return nil, fmt.Errorf("only one Spack environment can be activated")
+			}
+			// The --sh form does not require Spack's shell integration
+			cmds = append(cmds, "eval \"$(spack env activate --sh "+s.Args[0]+")\"")
+		case KindSpackLoad:
+			cmds = append(cmds, "eval \"$(spack load --sh "+strings.Join(s.Args, " ")+")\"")
+		case KindSource:
+			cmds = append(cmds, "source "+strings.Join(s.Args, " "))
+		default:
+			return nil, fmt.Errorf("invalid kind of step: %s", s.Kind)
+		}
+	}
+	return cmds, nil
+}
+
+// Script returns the shell script setting up the software environment, e.g., to include it in a batch script
+func (e *Env) Script() (string, error) {
+	cmds, err := e.Commands()
+	if err != nil || len(cmds) == 0 {
+		return "", err
+	}
+	return strings.Join(cmds, "\n") + "\n", nil
+}
+
+func runShell(script string, env []string, login bool) advexec.Result {
+	var cmd advexec.Advcmd
+	var res advexec.Result
+	var err error
+	cmd.BinPath, err = exec.LookPath("bash")
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	// module is usually a shell function so we need a login shell
+	shellArg := "-c"
+	if login {
+		shellArg = "-lc"
+	}
+	cmd.CmdArgs = []string{shellArg, script}
+	cmd.Env = env
+	return cmd.Run()
+}
+
+// validated records the software environments that were successfully validated, keyed by their commands, since
+// checking modules and Spack packages requires login shells and a job may be submitted many times (e.g., retries,
+// submission queues and campaigns). Failures are not recorded, the environment may be fixed in the meantime.
+var validated sync.Map
+
+// Validate checks, where possible, that the software environment can be set up: sourced files must exist,
+// modules must be available and Spack environments/packages must be known. Modules and Spack are not
+// checked when they are not available on the local node (e.g., the job runs on compute nodes only).
+// Once a given environment is successfully validated, it is not checked again.
+func (e *Env) Validate() error {
+	if e.IsEmpty() {
+		return nil
+	}
+	cmds, err := e.Commands()
+	if err != nil {
+		return err
+	}
+	key := strings.Join(cmds, "\n")
+	if _, ok := validated.Load(key); ok {
+		return nil
+	}
+	err = e.validate()
+	if err != nil {
+		return err
+	}
+	validated.Store(key, true)
+	return nil
+}
+
+// validate checks that the software environment can be set up, see Validate
+func (e *Env) validate() error {
+	modulesAvailable := e.usesModules() && runShell("type module", nil, true).Err == nil
+	_, spackErr := exec.LookPath("spack")
+	for _, s := range e.Steps {
+		switch s.Kind {
+		case KindModules:
+			if !modulesAvailable {
+				continue
+			}
+			for _, m := range s.Args {
+				if runShell("module is-avail "+m, nil, true).Err != nil {
+					return fmt.Errorf("module %s is not available", m)
+				}
+			}
+		case KindSpackEnv:
+			if strings.Contains(s.Args[0], "/") {
+				if !util.PathExists(filepath.Join(s.Args[0], "spack.yaml")) {
+					return fmt.Errorf("%s is not a Spack environment", s.Args[0])
+				}
+				continue
+			}
+			if spackErr != nil {
+				continue
+			}
+			res := runShell("spack env list", nil, false)
+			if res.Err == nil && !containsWord(res.Stdout, s.Args[0]) {
+				return fmt.Errorf("Spack environment %s does not exist", s.Args[0])
+			}
+		case KindSpackLoad:
+			if spackErr != nil {
+				continue
+			}
+			for _, spec := range s.Args {
+				if runShell("spack find "+spec, nil, false).Err != nil {
+					return fmt.Errorf("Spack package %s is not installed", spec)
+				}
+			}
+		case KindSource:
+			if !util.FileExists(s.Args[0]) {
+				return fmt.Errorf("%s does not exist", s.Args[0])
+			}
+		}
+	}
+	return nil
+}
+
+func containsWord(output string, word string) bool {
+	for _, w := range strings.Fields(output) {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEnv parses the output of 'env -0' that follows the marker
+func parseEnv(output string) ([]string, error) {
+	idx := strings.LastIndex(output, envMarker+"\n")
+	if idx == -1 {
+		return nil, fmt.Errorf("unable to find the environment in the output")
+	}
+	var env []string
+	for _, e := range strings.Split(output[idx+len(envMarker)+1:], "\x00") {
+		// Skip the variables set by the shell itself
+		if e == "" || strings.HasPrefix(e, "_=") || strings.HasPrefix(e, "SHLVL=") || strings.HasPrefix(e, "PWD=") {
+			continue
+		}
+		env = append(env, e)
+	}
+	return env, nil
+}
+
+// Apply sets up the software environment on the local node, starting from a given environment
+// (e.g., os.Environ()), and returns the resulting environment
+func (e *Env) Apply(environ []string) ([]string, error) {
+	if e.IsEmpty() {
+		return environ, nil
+	}
+	script, err := e.Script()
+	if err != nil {
+		return nil, err
+	}
+	script = "set -e\n" + script + "printf '\\n%s\\n' " + envMarker + "\nenv -0\n"
+	res := runShell(script, environ, e.usesModules())
+	if res.Err != nil {
+		return nil, fmt.Errorf("unable to set up the software environment: %w (stderr: %s)", res.Err, res.Stderr)
+	}
+	return parseEnv(res.Stdout)
+}
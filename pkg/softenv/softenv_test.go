@@ -0,0 +1,99 @@
+// Copyright (c) 2025, NVIDIA CORPORATION. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package softenv
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	e := FromModules([]string{"gcc/12", "openmpi/4.1.5"})
+	e.Steps = append(e.Steps, Step{Kind: KindSpackEnv, Args: []string{"myenv"}})
+	e.Steps = append(e.Steps, Step{Kind: KindSpackLoad, Args: []string{"hdf5", "fftw"}})
+	e.Steps = append(e.Steps, Step{Kind: KindSource, Args: []string{"/opt/setup.sh", "--quiet"}})
+	script, err := e.Script()
+	if err != nil {
+		t.Fatalf("Script() failed: %s", err)
+	}
+	expected := "module purge\nmodule load gcc/12 openmpi/4.1.5\neval \"$(spack env activate --sh myenv)\"\neval \"$(spack load --sh hdf5 fftw)\"\nsource /opt/setup.sh --quiet\n"
+	if script != expected {
+		t.Fatalf("Script() returned %q instead of %q", script, expected)
+	}
+
+	var empty *Env
+	script, err = empty.Script()
+	if err != nil || script != "" || !empty.IsEmpty() {
+		t.Fatalf("Script() returned %q, %v for an empty environment", script, err)
+	}
+
+	e = &Env{Steps: []Step{{Kind: "unknown", Args: []string{"foo"}}}}
+	_, err = e.Script()
+	if err == nil {
+		t.Fatalf("Script() succeeded with an invalid step")
+	}
+}
+
+func TestApply(t *testing.T) {
+	setupFile := filepath.Join(t.TempDir(), "setup.sh")
+	err := os.WriteFile(setupFile, []byte("echo setting up\nexport FOO=\"a b\"\nexport PATH=/opt/tool/bin:$PATH\n"), 0644)
+	if err != nil {
+		t.Fatalf("unable to create %s: %s", setupFile, err)
+	}
+	e := &Env{Steps: []Step{{Kind: KindSource, Args: []string{setupFile}}}}
+	err = e.Validate()
+	if err != nil {
+		t.Fatalf("Validate() failed: %s", err)
+	}
+
+	env, err := e.Apply([]string{"PATH=/usr/bin:/bin", "BAR=1"})
+	if err != nil {
+		t.Fatalf("Apply() failed: %s", err)
+	}
+	expected := map[string]bool{"PATH=/opt/tool/bin:/usr/bin:/bin": true, "BAR=1": true, "FOO=a b": true}
+	for _, v := range env {
+		delete(expected, v)
+	}
+	if len(expected) != 0 {
+		t.Fatalf("Apply() returned %s, missing %v", strings.Join(env, " "), expected)
+	}
+
+	e = &Env{Steps: []Step{{Kind: KindSource, Args: []string{filepath.Join(t.TempDir(), "missing.sh")}}}}
+	err = e.Validate()
+	if err == nil {
+		t.Fatalf("Validate() succeeded with a missing file")
+	}
+}
+
+func TestValidateOnce(t *testing.T) {
+	setupFile := filepath.Join(t.TempDir(), "setup.sh")
+	err := (&Env{Steps: []Step{{Kind: KindSource, Args: []string{setupFile}}}}).Validate()
+	if err == nil {
+		t.Fatalf("Validate() succeeded with a file that does not exist")
+	}
+
+	// A failed validation is not cached: the environment is checked again once fixed
+	err = os.WriteFile(setupFile, []byte("export FOO=1\n"), 0644)
+	if err != nil {
+		t.Fatalf("unable to create %s: %s", setupFile, err)
+	}
+	err = (&Env{Steps: []Step{{Kind: KindSource, Args: []string{setupFile}}}}).Validate()
+	if err != nil {
+		t.Fatalf("Validate() failed: %s", err)
+	}
+
+	// The same environment is not checked again, even through a different value
+	err = os.Remove(setupFile)
+	if err != nil {
+		t.Fatalf("unable to remove %s: %s", setupFile, err)
+	}
+	err = (&Env{Steps: []Step{{Kind: KindSource, Args: []string{setupFile}}}}).Validate()
+	if err != nil {
+		t.Fatalf("the environment was validated again: %s", err)
+	}
+}
@@ -15,4 +15,11 @@ type Config struct {
 
 	// CurPath is the path to the current directory
 	CurPath string
+
+	// OutputDir is the path to the directory where a directory is created for the output files of each job that
+	// does not set its own output directory (optional, output files are written to the run directory of the job if not set)
+	OutputDir string
+
+	// JobStoreDir is the path to the directory where the submitted jobs are recorded (optional, jobs are not recorded if not set)
+	JobStoreDir string
 }